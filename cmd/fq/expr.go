@@ -0,0 +1,527 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nicolaspasqualis/go-fq/fq"
+)
+
+// Filter expressions combine comparisons with and, or, not and parentheses:
+//
+//	(status = "active" or status = "pending") and price < 500 and not tags has "legacy"
+//
+// The grammar, keywords are case-insensitive:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field ( cmp value | [ "not" ] keyword operand | [ "not" ] "exists" )
+//	cmp        = "=" | "==" | "!=" | "<" | "<=" | ">" | ">=" | "~"
+//	keyword    = "has" value | "contains" string | "match" string
+//	           | "in" list | "within" "(" lat "," lng "," km ")"
+//	list       = "(" value { "," value } ")"
+//	value      = string | number | "true" | "false" | "null"
+//
+// Fields are dot paths (user.address.city, items.0.sku), strings are quoted with
+// double or single quotes and numbers are float64, like the numbers of JSON records.
+
+// syntaxError is an expression error at a 1-based column
+type syntaxError struct {
+	column int
+	msg    string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.column, e.msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOperator
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind   tokenKind
+	text   string      // source text, unquoted for strings
+	value  interface{} // number value
+	column int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// keyword reports whether t is the keyword word
+func (t token) keyword(word string) bool {
+	return t.kind == tokIdent && strings.EqualFold(t.text, word)
+}
+
+// lex splits an expression into tokens, ending with a tokEOF
+func lex(input string) ([]token, error) {
+	var tokens []token
+	column := 1
+
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		start := i
+		startColumn := column
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			column++
+			continue
+
+		case r == '(' || r == ')' || r == ',':
+			kind := map[rune]tokenKind{'(': tokLParen, ')': tokRParen, ',': tokComma}[r]
+			tokens = append(tokens, token{kind: kind, text: string(r), column: column})
+			i++
+			column++
+			continue
+
+		case strings.ContainsRune("=!<>~", r):
+			i++
+			if i < len(input) && input[i] == '=' && r != '~' {
+				i++
+			}
+			text := input[start:i]
+			if text == "!" {
+				return nil, &syntaxError{column: startColumn, msg: `unexpected "!", did you mean "!=" or "not"?`}
+			}
+			tokens = append(tokens, token{kind: tokOperator, text: text, column: startColumn})
+
+		case r == '"' || r == '\'':
+			text, n, err := lexString(input[i:], r)
+			if err != nil {
+				return nil, &syntaxError{column: startColumn, msg: err.Error()}
+			}
+			i += n
+			tokens = append(tokens, token{kind: tokString, text: text, column: startColumn})
+
+		case r == '-' || r == '.' || unicode.IsDigit(r):
+			i++
+			for i < len(input) && strings.IndexByte("0123456789.eE+-", input[i]) >= 0 {
+				// signs only follow exponents
+				if (input[i] == '+' || input[i] == '-') && input[i-1] != 'e' && input[i-1] != 'E' {
+					break
+				}
+				i++
+			}
+			num, err := strconv.ParseFloat(input[start:i], 64)
+			if err != nil {
+				return nil, &syntaxError{column: startColumn, msg: fmt.Sprintf("invalid number %q", input[start:i])}
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: input[start:i], value: num, column: startColumn})
+
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(input) {
+				r, size := utf8.DecodeRuneInString(input[i:])
+				if r != '_' && r != '.' && r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[start:i], column: startColumn})
+
+		default:
+			return nil, &syntaxError{column: startColumn, msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+
+		column += utf8.RuneCountInString(input[start:i])
+	}
+
+	return append(tokens, token{kind: tokEOF, column: column}), nil
+}
+
+// lexString reads the string literal quoted with quote at the start of input,
+// returning its unescaped value and its length in input
+func lexString(input string, quote rune) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		i += size
+
+		switch r {
+		case quote:
+			return b.String(), i, nil
+		case '\\':
+			if i == len(input) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			r, size = utf8.DecodeRuneInString(input[i:])
+			i += size
+			switch r {
+			case 'n':
+				r = '\n'
+			case 't':
+				r = '\t'
+			case '\\', '"', '\'':
+			default:
+				return "", 0, fmt.Errorf(`invalid escape "\%c" in string`, r)
+			}
+		}
+		b.WriteRune(r)
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// expr is a parsed filter expression
+type expr interface {
+	compile() fq.Query
+}
+
+type andExpr []expr
+
+type orExpr []expr
+
+type notExpr struct {
+	expr expr
+}
+
+// comparison is a field compared with the operator op, negated by not
+type comparison struct {
+	field  string
+	op     string
+	not    bool
+	values []interface{}
+}
+
+func (e andExpr) compile() fq.Query {
+	return fq.And(compileAll(e)...)
+}
+
+func (e orExpr) compile() fq.Query {
+	return fq.Or(compileAll(e)...)
+}
+
+func compileAll(exprs []expr) []fq.Query {
+	queries := make([]fq.Query, len(exprs))
+	for i, e := range exprs {
+		queries[i] = e.compile()
+	}
+	return queries
+}
+
+func (e notExpr) compile() fq.Query {
+	return fq.Not(e.expr.compile())
+}
+
+func (c comparison) compile() fq.Query {
+	var predicate fq.Query
+	switch c.op {
+	case "=", "==", "!=":
+		if c.values[0] != nil {
+			predicate = fq.Eq(c.values[0])
+		}
+	case "<":
+		predicate = fq.Lt(c.values[0])
+	case "<=":
+		predicate = fq.Lte(c.values[0])
+	case ">":
+		predicate = fq.Gt(c.values[0])
+	case ">=":
+		predicate = fq.Gte(c.values[0])
+	case "~", "match":
+		predicate = fq.Match(c.values[0])
+	case "contains":
+		predicate = fq.Contains(c.values[0].(string))
+	case "has":
+		predicate = fq.HasItem(c.values[0])
+	case "in":
+		predicate = fq.In(c.values...)
+	case "within":
+		predicate = fq.GeoWithin(c.values[0].(float64), c.values[1].(float64), c.values[2].(float64))
+	case "exists":
+		// fields set to null don't exist
+		if c.not {
+			return fq.Q{c.field: nil}
+		}
+		predicate = fq.Not(nil)
+	}
+
+	if c.not || c.op == "!=" {
+		predicate = fq.Not(predicate)
+	}
+	return fq.Q{c.field: predicate}
+}
+
+// parser is a recursive descent parser of filter expressions
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpr parses a filter expression into a Query
+func parseExpr(input string) (fq.Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokEOF {
+		return nil, p.errorf(next, "expected and, or or the end of the expression, got %s", next)
+	}
+	return e.compile(), nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &syntaxError{column: t.column, msg: fmt.Sprintf(format, args...)}
+}
+
+// expect consumes a token of kind, described as what in errors
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.errorf(t, "expected %s, got %s", what, t)
+	}
+	return t, nil
+}
+
+func (p *parser) or() (expr, error) {
+	e, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := orExpr{e}
+	for p.peek().keyword("or") {
+		p.next()
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *parser) and() (expr, error) {
+	e, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := andExpr{e}
+	for p.peek().keyword("and") {
+		p.next()
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *parser) unary() (expr, error) {
+	t := p.peek()
+	switch {
+	case t.keyword("not"):
+		p.next()
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+
+	case t.kind == tokLParen:
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (expr, error) {
+	field, err := p.expect(tokIdent, "a field")
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range []string{"and", "or", "not"} {
+		if field.keyword(word) {
+			return nil, p.errorf(field, "expected a field, got %s", field)
+		}
+	}
+
+	c := comparison{field: field.text}
+	op := p.next()
+
+	if op.kind == tokOperator {
+		c.op = op.text
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case c.op == "~":
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, p.errorf(p.tokens[p.pos-1], "expected a regular expression string")
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, p.errorf(p.tokens[p.pos-1], "invalid regular expression: %v", err)
+			}
+			value = re
+		case value == nil && c.op != "=" && c.op != "==" && c.op != "!=":
+			return nil, p.errorf(p.tokens[p.pos-1], "null can only be compared with = and !=")
+		}
+		c.values = []interface{}{value}
+		return c, nil
+	}
+
+	if op.keyword("not") {
+		c.not = true
+		op = p.next()
+	}
+	if op.kind == tokIdent {
+		c.op = strings.ToLower(op.text)
+	}
+
+	switch c.op {
+	case "exists":
+		return c, nil
+
+	case "has":
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		c.values = []interface{}{value}
+		return c, nil
+
+	case "contains", "match":
+		value, err := p.expect(tokString, "a string")
+		if err != nil {
+			return nil, err
+		}
+		c.values = []interface{}{value.text}
+		return c, nil
+
+	case "in":
+		c.values, err = p.list()
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+
+	case "within":
+		start := p.peek()
+		c.values, err = p.list()
+		if err != nil {
+			return nil, err
+		}
+		if len(c.values) != 3 {
+			return nil, p.errorf(start, "expected (lat, lng, km), got %d values", len(c.values))
+		}
+		for _, v := range c.values {
+			if _, ok := v.(float64); !ok {
+				return nil, p.errorf(start, "expected (lat, lng, km), got %v", v)
+			}
+		}
+		if c.values[2].(float64) < 0 {
+			return nil, p.errorf(start, "negative radius %v", c.values[2])
+		}
+		return c, nil
+	}
+
+	if c.not {
+		return nil, p.errorf(op, "expected has, contains, match, in, within or exists after not, got %s", op)
+	}
+	return nil, p.errorf(op, "expected an operator after %s, got %s", field.text, op)
+}
+
+// value parses a string, number, true, false or null
+func (p *parser) value() (interface{}, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokString:
+		return t.text, nil
+	case t.kind == tokNumber:
+		return t.value, nil
+	case t.keyword("true"):
+		return true, nil
+	case t.keyword("false"):
+		return false, nil
+	case t.keyword("null"):
+		return nil, nil
+	case t.kind == tokIdent:
+		return nil, p.errorf(t, "expected a value, got %s (quote strings)", t)
+	}
+	return nil, p.errorf(t, "expected a value, got %s", t)
+}
+
+// list parses a parenthesized, comma-separated list of values
+func (p *parser) list() ([]interface{}, error) {
+	if _, err := p.expect(tokLParen, `"("`); err != nil {
+		return nil, err
+	}
+
+	var values []interface{}
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		t := p.next()
+		if t.kind == tokRParen {
+			return values, nil
+		}
+		if t.kind != tokComma {
+			return nil, p.errorf(t, `expected "," or ")", got %s`, t)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nicolaspasqualis/go-fq/fq"
+)
+
+func TestParseExpr(t *testing.T) {
+	var records []interface{}
+	for _, line := range []string{
+		`{"id": 1, "status": "active", "price": 999, "tags": ["legacy", "work"], "user": {"email": "ana@example.com"}, "loc": [48.85, 2.35]}`,
+		`{"id": 2, "status": "pending", "price": 20, "tags": ["read"], "user": {"email": "bob@example.org"}, "loc": [40.71, -74.0]}`,
+		`{"id": 3, "status": "active", "price": 45, "tags": ["sale"], "user": null, "on sale": true}`,
+		`{"id": 4, "status": "archived", "price": 300, "tags": [], "discount": 10}`,
+	} {
+		var record interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid test record: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected []float64
+	}{
+		{"equality", `status = "active"`, []float64{1, 3}},
+		{"double equals and single quotes", `status == 'pending'`, []float64{2}},
+		{"not equal", `status != "active"`, []float64{2, 4}},
+		{"comparisons", `price >= 45 and price < 999`, []float64{3, 4}},
+		{"negative numbers", `loc exists and loc.1 < -1e1`, []float64{2}},
+		{"request example", `(status = "active" or status = "pending") and price < 500 and not tags has "legacy"`, []float64{2, 3}},
+		{"precedence", `status = "archived" or status = "active" and price < 100`, []float64{3, 4}},
+		{"parentheses", `(status = "archived" or status = "active") and price < 100`, []float64{3}},
+		{"not", `not (status = "active" or price > 100)`, []float64{2}},
+		{"keywords are case-insensitive", `status = "active" AND NOT price > 100`, []float64{3}},
+		{"repeated field", `price > 20 and price < 500`, []float64{3, 4}},
+		{"in", `id in (1, 3)`, []float64{1, 3}},
+		{"not in", `status not in ("active", "archived")`, []float64{2}},
+		{"has", `tags has "sale"`, []float64{3}},
+		{"contains", `user.email contains ".org"`, []float64{2}},
+		{"match", `status match "ACT"`, []float64{1, 3}},
+		{"regex", `user.email ~ "^[ab].*\\.com$"`, []float64{1}},
+		{"exists", `discount exists`, []float64{4}},
+		{"not exists", `user not exists`, []float64{3, 4}},
+		{"null", `user = null or discount != null`, []float64{3, 4}},
+		{"booleans", `discount exists or tags has true`, []float64{4}},
+		{"within", `loc within (48.86, 2.34, 5)`, []float64{1}},
+		{"array index", `tags.0 = "legacy"`, []float64{1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := parseExpr(tc.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result, err := fq.Filter(records, query, 0, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var ids []float64
+			for _, record := range result {
+				ids = append(ids, record.(map[string]interface{})["id"].(float64))
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("Expected ids %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	records := []interface{}{
+		map[string]interface{}{"id": 1.0, "status": "a:b:c", "first name": "ana", "price": 5.0},
+		map[string]interface{}{"id": 2.0, "status": "b", "first name": "bob", "price": 50.0},
+	}
+
+	tests := []struct {
+		name     string
+		filters  []string
+		expected []float64
+	}{
+		{"expression value with colons", []string{`status = "a:b:c"`}, []float64{1}},
+		{"legacy filter", []string{"status:eq:b"}, []float64{2}},
+		{"legacy field with a space", []string{"first name:eq:ana"}, []float64{1}},
+		{"legacy value with colons", []string{"status:eq:a:b:c"}, []float64{1}},
+		{"legacy and expressions", []string{"price:gt:1", `status != "b"`}, []float64{1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := parseFilters(tc.filters)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result, err := fq.Filter(records, query, 0, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var ids []float64
+			for _, record := range result {
+				ids = append(ids, record.(map[string]interface{})["id"].(float64))
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("Expected ids %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+
+	if _, err := parseFilters([]string{"price:between:1"}); err == nil || !strings.Contains(err.Error(), "unknown operator: between") {
+		t.Errorf("Expected an unknown operator error, got %v", err)
+	}
+	if _, err := parseFilters([]string{`status = "a:b:c`}); err == nil || !strings.Contains(err.Error(), "unterminated string") {
+		t.Errorf("Expected the expression error, got %v", err)
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		expr           string
+		column         int
+		errorSubstring string
+	}{
+		{``, 1, "expected a field, got end of expression"},
+		{`price`, 6, "expected an operator after price"},
+		{`price < `, 9, "expected a value"},
+		{`status = active`, 10, `got "active" (quote strings)`},
+		{`(price < 5`, 11, `expected ")"`},
+		{`price < 5)`, 10, "expected and, or or the end"},
+		{`price < 5 or`, 13, "expected a field"},
+		{`and price < 5`, 1, `expected a field, got "and"`},
+		{`price ! 5`, 7, `unexpected "!"`},
+		{`name = "abc`, 8, "unterminated string"},
+		{`name = "a\qb"`, 8, `invalid escape "\q"`},
+		{`price < 1.2.3`, 9, `invalid number "1.2.3"`},
+		{`price < 5 # comment`, 11, "unexpected character '#'"},
+		{`price < null`, 9, "null can only be compared with = and !="},
+		{`name ~ "("`, 8, "invalid regular expression"},
+		{`name contains 5`, 15, "expected a string"},
+		{`id in 1, 2`, 7, `expected "("`},
+		{`id in (1 2)`, 10, `expected "," or ")"`},
+		{`loc within (1, 2)`, 12, "expected (lat, lng, km)"},
+		{`loc within (1, 2, -3)`, 12, "negative radius"},
+		{`tags not = "a"`, 10, "expected has, contains, match, in, within or exists after not"},
+		{`price @ 5`, 7, "unexpected character '@'"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := parseExpr(tc.expr)
+			var syntaxErr *syntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a *syntaxError, got %v", err)
+			}
+			if syntaxErr.column != tc.column {
+				t.Errorf("Expected the error at column %d, got %v", tc.column, err)
+			}
+			if !strings.Contains(err.Error(), tc.errorSubstring) {
+				t.Errorf("Expected error containing %q, got: %v", tc.errorSubstring, err)
+			}
+		})
+	}
+}
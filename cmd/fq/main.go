@@ -1,28 +1,79 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"unicode"
 
 	"github.com/nicolaspasqualis/go-fq/fq"
 )
 
-const usage = `Usage: fq [options] <data-file> [filters...]
+const usage = `Usage: fq [options] [files...] [--] [filters...]
+
+Reads JSONL records from the files, processed in sequence, or from stdin when there
+are none or a file is "-". Files can be globs (logs/*.jsonl), gzip and bzip2 input
+is decompressed. Arguments are files until the first one that reads as a filter,
+or all the arguments before -- are.
 
 Options:
   -skip <number>           Skip first N results
   -limit <number>          Limit to N results
   -quiet                   Suppress error messages
+  -with-filename           Output {"file": ..., "record": ...} with the file of each record
+  -n                       Output {"line": ..., "record": ...} with the JSONL line of each record
+  -follow                  Keep reading the lines appended to a JSONL file, like tail -F,
+                           until interrupted. Truncated or rotated files are read again,
+                           -n numbering lines from the first line read
+  -explain <number>        Explain why the first N non-matching records didn't match (stderr)
+  -max-line-size <bytes>   Report longer lines as errors instead of reading them (default no limit),
+                           and longer JSON array elements once they're read
+  -input <format>          Input format: jsonl (default), csv or tsv, with a header row.
+                           Numbers, booleans and RFC 3339 timestamps of csv and tsv are typed.
+                           Input starting with [ is read as a JSON array, one element at a time
+  -array-path <path>       Read the elements of a nested JSON array (.data.items)
+  -output <format>         Output format: jsonl (default), json (an array), csv, tsv or table.
+                           The columns of csv, tsv and table are the -select fields, or the
+                           fields of the first 100 results. -follow writes their rows as they come
+  -q <json>                Mongo-style JSON query, combined with the filters
+                           ({"price": {"$lt": 500}, "$or": [{"tags": "sale"}, ...]})
+  -select <fields>         Only output these comma-separated fields (name,price,user.email),
+                           alias=path renames a field (email=user.email)
+  -group-by <field>        Output a row per value of field with the -agg aggregates
+  -agg <aggregates>        Comma-separated aggregates for -group-by (default count):
+                           count, sum:field, avg:field, min:field, max:field,
+                           alias= renames one (total=sum:price)
   -help                    Show this help
 
 Filters:
-  field:operator:value
-
-Operators:
+  field:operator:value     field can be a dot path (user.address.city, items.0.sku)
+  <expression>             comparisons combined with and, or, not and parentheses:
+                           (status = "active" or status = "pending") and price < 500
+                           Records must match all the filters.
+
+Expression operators:
+  = != < <= > >=           Compare with a "string", number, true, false or null
+  ~ "regexp"               Regular expression match
+  has value                Array contains value
+  contains "text"          String contains substring
+  match "text"             Case-insensitive text match
+  in (a, b, ...)           Value in list
+  within (lat, lon, km)    Geospatial within radius
+  exists                   Field is set and not null
+  not has/contains/match/in/within/exists negates them
+
+Filter operators:
   eq         Equal to
   gt         Greater than
   lt         Less than
@@ -36,8 +87,18 @@ Operators:
 
 Examples:
   fq data.jsonl "price:lt:500"
+  cat data.jsonl | fq "price:lt:500"
+  fq -follow app.log 'level = "error"'
+  fq -with-filename "logs/*.jsonl" 'level = "error"'
+  fq -n -with-filename "logs/*.jsonl" 'level = "error"'
   fq data.jsonl "status:eq:active" "category:in:electronics,books"
   fq data.jsonl "location:geowithin:40.7,-74.0,10"
+  fq data.jsonl "user.address.city:eq:Paris"
+  fq data.jsonl '(status = "active" or status = "pending") and not tags has "legacy"'
+  fq -q '{"price": {"$lt": 500}, "tags": {"$all": ["sale"]}}' data.jsonl
+  fq -select name,city=user.address.city data.jsonl "price:lt:500"
+  fq -group-by category -agg count,sum:price,avg:rating data.jsonl
+  fq -output table -select name,price data.jsonl "price < 500"
 `
 
 func main() {
@@ -46,16 +107,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	var skip, limit int
-	var quiet, help bool
+	var skip, limit, explain, maxLineSize int
+	var quiet, help, withFilename, lineNumbers, follow bool
+	var selection, groupBy, aggs, jsonQuery, arrayPath string
+	input, outputFormat := "jsonl", "jsonl"
 
 	args := os.Args[1:]
-	var dataFile string
-	var filters []string
+	var files, filters []string
+	// the arguments after -- are filters, the positional ones before it files
+	separator := slices.Index(args, "--")
 
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 		switch {
+		case separator >= 0 && i > separator:
+			filters = append(filters, arg)
+		case i == separator:
 		case arg == "-skip" && i+1 < len(args):
 			if val, err := strconv.Atoi(args[i+1]); err == nil {
 				skip = val
@@ -66,12 +133,51 @@ func main() {
 				limit = val
 			}
 			i++
+		case arg == "-explain" && i+1 < len(args):
+			if val, err := strconv.Atoi(args[i+1]); err == nil {
+				explain = val
+			}
+			i++
+		case arg == "-max-line-size" && i+1 < len(args):
+			if val, err := strconv.Atoi(args[i+1]); err == nil {
+				maxLineSize = val
+			}
+			i++
+		case arg == "-input" && i+1 < len(args):
+			input = args[i+1]
+			i++
+		case arg == "-output" && i+1 < len(args):
+			outputFormat = args[i+1]
+			i++
+		case arg == "-array-path" && i+1 < len(args):
+			arrayPath = args[i+1]
+			i++
+		case arg == "-q" && i+1 < len(args):
+			jsonQuery = args[i+1]
+			i++
+		case arg == "-select" && i+1 < len(args):
+			selection = args[i+1]
+			i++
+		case arg == "-group-by" && i+1 < len(args):
+			groupBy = args[i+1]
+			i++
+		case arg == "-agg" && i+1 < len(args):
+			aggs = args[i+1]
+			i++
 		case arg == "-quiet":
 			quiet = true
+		case arg == "-with-filename":
+			withFilename = true
+		case arg == "-n":
+			lineNumbers = true
+		case arg == "-follow":
+			follow = true
 		case arg == "-help":
 			help = true
-		case !strings.HasPrefix(arg, "-") && dataFile == "":
-			dataFile = arg
+		case arg == "-" && filters == nil:
+			files = append(files, arg)
+		case !strings.HasPrefix(arg, "-") && filters == nil && (separator >= 0 || isFile(arg)):
+			files = append(files, arg)
 		case !strings.HasPrefix(arg, "-"):
 			filters = append(filters, arg)
 		}
@@ -82,9 +188,21 @@ func main() {
 		return
 	}
 
-	if dataFile == "" {
+	if separator < 0 {
+		for _, file := range files {
+			if err := missingFile(file); err != nil {
+				if !quiet {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+				os.Exit(1)
+			}
+		}
+	}
+
+	inputs, err := expandFiles(files)
+	if err != nil {
 		if !quiet {
-			fmt.Fprintf(os.Stderr, "Error: data file required\n")
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
 		os.Exit(1)
 	}
@@ -97,10 +215,90 @@ func main() {
 		os.Exit(1)
 	}
 
-	dataCh, srcErrCh := fq.JSONLFileSourceStream(dataFile)
-	resultCh, filterErrCh := fq.FilterC(dataCh, query, skip, limit)
+	if jsonQuery != "" {
+		parsed, err := fq.ParseJSONQuery([]byte(jsonQuery))
+		if err != nil {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Error parsing -q: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		if query == nil {
+			query = parsed
+		} else {
+			query = fq.And(parsed, query)
+		}
+	}
 
-	if err := process(resultCh, srcErrCh, filterErrCh, quiet); err != nil {
+	columns, err := parseSelect(selection)
+	if err != nil {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Error parsing -select: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	grouping, err := parseGrouping(groupBy, aggs)
+	if err != nil {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Error parsing aggregation: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	if withFilename && grouping != nil {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Error: -with-filename can't be used with -group-by\n")
+		}
+		os.Exit(1)
+	}
+	if lineNumbers && grouping != nil {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Error: -n can't be used with -group-by\n")
+		}
+		os.Exit(1)
+	}
+
+	var explaining *explainer
+	if explain > 0 && query != nil {
+		explaining = &explainer{query: query, n: explain}
+	}
+
+	source, err := inputSource(input, maxLineSize, arrayPath, lineNumbers)
+	if err != nil {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	if follow {
+		if err := checkFollow(inputs, input, arrayPath); err != nil {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		// stop following on ^C, writing the results of -group-by or -output json
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		source = followSource(ctx, maxLineSize)
+	}
+
+	out, err := newWriter(outputFormat, os.Stdout, follow)
+	if err != nil {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	results := paginate(filterInputs(inputs, source, query, explaining), skip, limit)
+	err = process(results, out, columns, grouping, withFilename, lineNumbers, quiet)
+	if closeErr := out.close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		if !quiet {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
@@ -108,18 +306,37 @@ func main() {
 	}
 }
 
+// legacyFilter matches the field:operator:value filters that aren't expressions. The field
+// can hold anything but the characters of expression operators, spaces included.
+var legacyFilter = regexp.MustCompile(`^[^=<>"():]+:[a-z]+:`)
+
+// parseFilters parses filter expressions and field:operator:value filters, matching the
+// records that match all of them. A filter is only read as field:operator:value when it
+// isn't a valid expression, so values of expressions can hold colons.
 func parseFilters(filters []string) (fq.Query, error) {
 	if len(filters) == 0 {
 		return nil, nil
 	}
 
-	query := fq.Q{}
+	fields := fq.Q{}
+	var queries []fq.Query
 	for _, filter := range filters {
-		parts := strings.SplitN(filter, ":", 3)
-		if len(parts) != 3 {
-			return nil, fmt.Errorf("invalid filter format: %s", filter)
+		query, err := parseExpr(filter)
+		if err == nil {
+			queries = append(queries, query)
+			continue
+		}
+		if !legacyFilter.MatchString(filter) {
+			var syntaxErr *syntaxError
+			if errors.As(err, &syntaxErr) {
+				// point at the error under the expression
+				prefix := []rune(filter)[:syntaxErr.column-1]
+				return nil, fmt.Errorf("invalid filter format: %v\n  %s\n  %s^", err, filter, strings.Repeat(" ", len(prefix)))
+			}
+			return nil, err
 		}
 
+		parts := strings.SplitN(filter, ":", 3)
 		field, operator, value := parts[0], parts[1], parts[2]
 
 		predicate, err := createPredicate(operator, value)
@@ -127,10 +344,20 @@ func parseFilters(filters []string) (fq.Query, error) {
 			return nil, err
 		}
 
-		query[field] = predicate
+		if existing, ok := fields[field]; ok {
+			fields[field] = fq.And(existing, predicate)
+		} else {
+			fields[field] = predicate
+		}
 	}
 
-	return query, nil
+	if len(fields) > 0 {
+		queries = append([]fq.Query{fields}, queries...)
+	}
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	return fq.And(queries...), nil
 }
 
 var operatorFuncs = map[string]interface{}{
@@ -271,62 +498,497 @@ func parseCommaSeparated(value string) []string {
 	return result
 }
 
-func process(resultCh <-chan interface{}, srcErrCh, filterErrCh <-chan error, quiet bool) error {
-	errorCh := make(chan error, 10)
-	done := make(chan struct{})
+// isFile reports whether a positional argument is an input file rather than a filter:
+// an existing path, or anything that isn't a valid filter, like a glob or a missing file
+// (see missingFile). Spaces, expression operators and quotes only appear in filters.
+func isFile(arg string) bool {
+	if _, err := os.Stat(arg); err == nil {
+		return true
+	}
+	if legacyFilter.MatchString(arg) || strings.ContainsFunc(arg, unicode.IsSpace) || strings.ContainsAny(arg, `=<>~!"'()`) {
+		return false
+	}
+	_, err := parseFilters([]string{arg})
+	return err != nil
+}
 
-	go func() {
-		defer close(done)
-		defer close(errorCh)
-		for {
-			select {
-			case err, ok := <-srcErrCh:
-				if !ok {
-					srcErrCh = nil
-				} else {
-					errorCh <- fmt.Errorf("source: %v", err)
-					if !quiet {
-						fmt.Fprintf(os.Stderr, "Source error: %v\n", err)
+// missingFile returns the error of a file argument that doesn't exist, along with the error
+// of reading it as a filter, since it's only a file because it isn't a valid filter
+func missingFile(arg string) error {
+	if arg == "-" || strings.ContainsAny(arg, "*?[") {
+		return nil
+	}
+	_, statErr := os.Stat(arg)
+	if !os.IsNotExist(statErr) {
+		return nil
+	}
+	_, filterErr := parseFilters([]string{arg})
+	return fmt.Errorf("%v, and %s isn't a valid filter either (put filters after --): %v", statErr, arg, filterErr)
+}
+
+// expandFiles expands the globs of files, stdin being "-" and the only input without files
+func expandFiles(files []string) ([]string, error) {
+	if len(files) == 0 {
+		return []string{"-"}, nil
+	}
+
+	var inputs []string
+	for _, file := range files {
+		if file == "-" || !strings.ContainsAny(file, "*?[") {
+			inputs = append(inputs, file)
+			continue
+		}
+		matches, err := filepath.Glob(file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %v", file, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s", file)
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
+// match is a result with the input it comes from and its line number, 0 if unknown
+type match struct {
+	file  string
+	line  int
+	value interface{}
+}
+
+// source reads the records of an input file, or stdin for "-"
+type source func(input string) iter.Seq2[fq.Record, error]
+
+// inputSource returns the source of the -input format, JSONL or JSON arrays for jsonl.
+// lineNumbers reads JSONL records with their line number.
+func inputSource(format string, maxLineSize int, arrayPath string, lineNumbers bool) (source, error) {
+	if arrayPath != "" && format != "jsonl" {
+		return nil, fmt.Errorf("-array-path requires JSON input")
+	}
+	if lineNumbers && (format != "jsonl" || arrayPath != "") {
+		return nil, fmt.Errorf("-n requires JSONL input")
+	}
+
+	var opts []fq.CSVOption
+	switch format {
+	case "jsonl":
+		if lineNumbers {
+			return func(input string) iter.Seq2[fq.Record, error] {
+				if input == "-" {
+					return numbered(fq.JSONReaderRecordSeq(os.Stdin, fq.WithMaxLineSize(maxLineSize)))
+				}
+				return numbered(fq.JSONFileRecordSeq(input, fq.WithMaxLineSize(maxLineSize)))
+			}, nil
+		}
+
+		jsonOpts := []fq.SourceOption{fq.WithMaxLineSize(maxLineSize), fq.WithArrayPath(arrayPath)}
+		return func(input string) iter.Seq2[fq.Record, error] {
+			if input == "-" {
+				return records(fq.JSONReaderSourceSeq(os.Stdin, jsonOpts...))
+			}
+			return records(fq.JSONFileSourceSeq(input, jsonOpts...))
+		}, nil
+	case "csv":
+		opts = []fq.CSVOption{fq.WithTypeInference()}
+	case "tsv":
+		opts = []fq.CSVOption{fq.WithTypeInference(), fq.WithDelimiter('\t')}
+	default:
+		return nil, fmt.Errorf("unknown input format %q, expected jsonl, csv or tsv", format)
+	}
+
+	return func(input string) iter.Seq2[fq.Record, error] {
+		if input == "-" {
+			return records(fq.CSVSourceSeq(os.Stdin, opts...))
+		}
+		return records(fq.CSVFileSourceSeq(input, opts...))
+	}, nil
+}
+
+// numbered reports the JSON arrays of seq, read as arrays without -n, as -n input errors
+func numbered(seq iter.Seq2[fq.Record, error]) iter.Seq2[fq.Record, error] {
+	return func(yield func(fq.Record, error) bool) {
+		for record, err := range seq {
+			if errors.Is(err, fq.ErrJSONArray) {
+				err = fmt.Errorf("-n requires JSONL input, not a JSON array")
+			}
+			if !yield(record, err) {
+				return
+			}
+		}
+	}
+}
+
+// records wraps the values of seq in records without line numbers
+func records(seq iter.Seq2[interface{}, error]) iter.Seq2[fq.Record, error] {
+	return func(yield func(fq.Record, error) bool) {
+		for value, err := range seq {
+			if !yield(fq.Record{Value: value}, err) {
+				return
+			}
+		}
+	}
+}
+
+// checkFollow returns an error if the inputs can't be followed with -follow
+func checkFollow(inputs []string, format, arrayPath string) error {
+	switch {
+	case len(inputs) != 1 || inputs[0] == "-":
+		return fmt.Errorf("-follow requires a single file")
+	case format != "jsonl" || arrayPath != "":
+		return fmt.Errorf("-follow requires JSONL input")
+	}
+	return nil
+}
+
+// followSource returns the source of the lines appended to a JSONL file until ctx is done,
+// lines being numbered from the first line read
+func followSource(ctx context.Context, maxLineSize int) source {
+	return func(input string) iter.Seq2[fq.Record, error] {
+		return func(yield func(fq.Record, error) bool) {
+			for record, err := range fq.FollowJSONLRecordSeq(ctx, input, fq.WithMaxLineSize(maxLineSize)) {
+				// interrupted, not an error
+				if err != nil && err == ctx.Err() {
+					return
+				}
+				if !yield(record, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// filterInputs filters the records of the inputs read from source in sequence,
+// explaining the non-matching ones when explainer isn't nil
+func filterInputs(inputs []string, source source, query fq.Query, explainer *explainer) iter.Seq2[match, error] {
+	return func(yield func(match, error) bool) {
+		for _, input := range inputs {
+			records := source(input)
+
+			name := ""
+			if len(inputs) > 1 {
+				name = input
+			}
+			records = sourceErrors(name, records)
+			if explainer != nil {
+				records = explainer.explain(name, records)
+			}
+
+			// queries are evaluated against the Value of the records
+			for record, err := range fq.FilterSeq2(records, query, 0, 0) {
+				if !yield(match{file: input, line: record.Line, value: record.Value}, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// paginate skips the first skip matches and stops after limit matches if limit > 0,
+// passing errors through
+func paginate(results iter.Seq2[match, error], skip, limit int) iter.Seq2[match, error] {
+	return func(yield func(match, error) bool) {
+		count := 0
+		for result, err := range results {
+			if err == nil {
+				if skip > 0 {
+					skip--
+					continue
+				}
+				count++
+			}
+			if !yield(result, err) || (limit > 0 && count == limit) {
+				return
+			}
+		}
+	}
+}
+
+// sourceError marks the errors of the data source, to tell them apart from filter errors
+type sourceError struct {
+	err error
+}
+
+func (e sourceError) Error() string {
+	return e.err.Error()
+}
+
+// sourceErrors wraps the errors of records in sourceError, prefixed with name if any
+func sourceErrors(name string, records iter.Seq2[fq.Record, error]) iter.Seq2[fq.Record, error] {
+	return func(yield func(fq.Record, error) bool) {
+		for record, err := range records {
+			if err != nil {
+				if name != "" {
+					err = fmt.Errorf("%s: %w", name, err)
+				}
+				err = sourceError{err}
+			}
+			if !yield(record, err) {
+				return
+			}
+		}
+	}
+}
+
+// explainer prints the explanation of the first n records that don't match query to stderr
+type explainer struct {
+	query fq.Query
+	n     int
+}
+
+// explain passes the records of the input name through, explaining the ones that don't match
+func (e *explainer) explain(name string, records iter.Seq2[fq.Record, error]) iter.Seq2[fq.Record, error] {
+	return func(yield func(fq.Record, error) bool) {
+		record := 0
+		for item, err := range records {
+			if err == nil {
+				record++
+				if e.n > 0 {
+					if explanation := fq.Explain(e.query, item); !explanation.Result {
+						if name != "" {
+							fmt.Fprintf(os.Stderr, "Record %d of %s did not match:\n%s", record, name, explanation)
+						} else {
+							fmt.Fprintf(os.Stderr, "Record %d did not match:\n%s", record, explanation)
+						}
+						e.n--
 					}
 				}
-			case err, ok := <-filterErrCh:
-				if !ok {
-					filterErrCh = nil
+			}
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+}
+
+// process writes results to out, grouped when grouping isn't nil, tagged with their file when
+// withFilename is true and their line number when lineNumbers is true, reporting errors as they
+// come. It returns the first error once all results are processed.
+func process(results iter.Seq2[match, error], out writer, columns []column, grouping *grouping, withFilename, lineNumbers, quiet bool) error {
+	var firstErr error
+
+	matches := func(yield func(match) bool) {
+		for result, err := range results {
+			if err != nil {
+				var srcErr sourceError
+				if errors.As(err, &srcErr) {
+					err = fmt.Errorf("source: %v", srcErr.err)
+					if !quiet {
+						fmt.Fprintf(os.Stderr, "Source error: %v\n", srcErr.err)
+					}
 				} else {
-					errorCh <- fmt.Errorf("filter: %v", err)
 					if !quiet {
 						fmt.Fprintf(os.Stderr, "Filter error: %v\n", err)
 					}
+					err = fmt.Errorf("filter: %v", err)
 				}
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+
+			if !yield(result) {
+				return
+			}
+		}
+	}
+
+	if grouping != nil {
+		records := func(yield func(interface{}) bool) {
+			for m := range matches {
+				if !yield(m.value) {
+					return
+				}
+			}
+		}
+
+		rows := fq.GroupBySeq(records, grouping.key, grouping.aggs...)
+		for _, row := range rows {
+			if err := out.write(grouping.record(row, columns)); err != nil {
+				return err
+			}
+		}
+		return firstErr
+	}
+
+	fields := columnFields(columns)
+	for m := range matches {
+		result := m.value
+		if columns != nil {
+			result = record{columns: columns, values: fq.Project(result, fields)}
+		}
+		if withFilename || lineNumbers {
+			tagged := sourceRecord{Record: result}
+			if withFilename {
+				tagged.File = m.file
 			}
-			if srcErrCh == nil && filterErrCh == nil {
-				break
+			if lineNumbers {
+				tagged.Line = m.line
 			}
+			result = tagged
+		}
+
+		if err := out.write(result); err != nil {
+			return err
 		}
-	}()
+	}
+
+	return firstErr
+}
 
-	outputErr := output(resultCh)
+// sourceRecord is a result output with -with-filename or -n, File is "-" for stdin
+type sourceRecord struct {
+	File   string      `json:"file,omitempty"`
+	Line   int         `json:"line,omitempty"`
+	Record interface{} `json:"record"`
+}
 
-	<-done
+// column is a field selected with -select, output as name
+type column struct {
+	name string
+	path string
+}
 
-	if outputErr != nil {
-		return outputErr
+// parseSelect parses a comma-separated list of fields, each a path or an alias=path
+func parseSelect(selection string) ([]column, error) {
+	if selection == "" {
+		return nil, nil
 	}
-	select {
-	case err := <-errorCh:
-		return err
-	default:
-		return nil
+
+	var columns []column
+	seen := map[string]bool{}
+	for _, field := range strings.Split(selection, ",") {
+		field = strings.TrimSpace(field)
+		name, path, renamed := strings.Cut(field, "=")
+		if !renamed {
+			path = name
+		}
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+
+		if name == "" || path == "" {
+			return nil, fmt.Errorf("invalid field: %q", field)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate field: %s", name)
+		}
+		seen[name] = true
+
+		columns = append(columns, column{name: name, path: path})
 	}
+	return columns, nil
 }
 
-func output(resultCh <-chan interface{}) error {
-	for result := range resultCh {
-		bytes, err := json.Marshal(result)
+// columnFields returns the projection of columns
+func columnFields(columns []column) fq.Fields {
+	fields := make(fq.Fields, len(columns))
+	for _, c := range columns {
+		fields[c.name] = c.path
+	}
+	return fields
+}
+
+// record is a projected result, marshaled with its fields in -select order
+type record struct {
+	columns []column
+	values  map[string]interface{}
+}
+
+func (r record) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, c := range r.columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(c.name)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		fmt.Println(string(bytes))
+		value, err := json.Marshal(r.values[c.name])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
 	}
-	return nil
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+// grouping is the aggregation requested with -group-by and -agg
+type grouping struct {
+	key  string
+	aggs []fq.Aggregator
+}
+
+var aggregators = map[string]func(field string) fq.Aggregator{
+	"sum": fq.Sum,
+	"avg": fq.Avg,
+	"min": fq.Min,
+	"max": fq.Max,
+}
+
+// parseGrouping parses the -group-by field and the comma-separated -agg list,
+// each a count or an op:field, optionally prefixed by alias=
+func parseGrouping(key, aggs string) (*grouping, error) {
+	if key == "" {
+		if aggs != "" {
+			return nil, fmt.Errorf("-agg requires -group-by")
+		}
+		return nil, nil
+	}
+
+	g := &grouping{key: key}
+	if aggs == "" {
+		g.aggs = []fq.Aggregator{fq.Count()}
+		return g, nil
+	}
+
+	seen := map[string]bool{key: true}
+	for _, spec := range strings.Split(aggs, ",") {
+		spec = strings.TrimSpace(spec)
+		alias, agg, renamed := strings.Cut(spec, "=")
+		if !renamed {
+			agg = alias
+		}
+
+		var aggregator fq.Aggregator
+		op, field, hasField := strings.Cut(agg, ":")
+		switch {
+		case op == "count" && !hasField:
+			aggregator = fq.Count()
+		case aggregators[op] != nil && field != "":
+			aggregator = aggregators[op](field)
+		default:
+			return nil, fmt.Errorf("invalid aggregate: %q", spec)
+		}
+
+		if renamed {
+			aggregator = aggregator.As(alias)
+		}
+		if seen[aggregator.Name()] {
+			return nil, fmt.Errorf("duplicate field: %s", aggregator.Name())
+		}
+		seen[aggregator.Name()] = true
+
+		g.aggs = append(g.aggs, aggregator)
+	}
+	return g, nil
+}
+
+// record returns a group row with its fields in -select order if any,
+// the group key then the aggregates otherwise
+func (g *grouping) record(row map[string]interface{}, columns []column) record {
+	if columns != nil {
+		return record{columns: columns, values: fq.Project(row, columnFields(columns))}
+	}
+
+	columns = []column{{name: g.key}}
+	for _, agg := range g.aggs {
+		columns = append(columns, column{name: agg.Name()})
+	}
+	return record{columns: columns, values: row}
 }
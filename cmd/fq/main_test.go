@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -43,7 +50,12 @@ func createTestData(t *testing.T) string {
 }
 
 func runCLI(args ...string) (stdout, stderr string, exitCode int) {
+	return runCLIWithStdin("", args...)
+}
+
+func runCLIWithStdin(stdin string, args ...string) (stdout, stderr string, exitCode int) {
 	cmd := exec.Command("./testfq", args...)
+	cmd.Stdin = strings.NewReader(stdin)
 	
 	stdoutBytes, err := cmd.Output()
 	stdout = string(stdoutBytes)
@@ -105,6 +117,12 @@ func TestBasicFiltering(t *testing.T) {
 			contains: []string{"headphones"},
 			notContains: []string{"laptop", "smartphone", "book"},
 		},
+		{
+			name:     "no filters outputs every record",
+			args:     []string{testFile},
+			wantExit: 0,
+			contains: []string{"laptop", "book", "smartphone", "desk", "headphones", "chair"},
+		},
 	}
 	
 	for _, tt := range tests {
@@ -153,6 +171,78 @@ func TestOutputFormats(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "json_format",
+			args:     []string{"-output", "json", testFile, "category:eq:furniture"},
+			wantExit: 0,
+			check: func(t *testing.T, stdout string) {
+				var results []map[string]interface{}
+				if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+					t.Fatalf("Output should be a JSON array: %v\n%s", err, stdout)
+				}
+				if len(results) != 2 || results[0]["name"] != "desk" || results[1]["name"] != "chair" {
+					t.Errorf("Unexpected results %v", results)
+				}
+			},
+		},
+		{
+			name:     "empty_json_format",
+			args:     []string{"-output", "json", testFile, "price:gt:5000"},
+			wantExit: 0,
+			check: func(t *testing.T, stdout string) {
+				if stdout != "[]\n" {
+					t.Errorf("Expected an empty array, got %q", stdout)
+				}
+			},
+		},
+		{
+			name:     "csv_format",
+			args:     []string{"-output", "csv", testFile, "category:eq:furniture"},
+			wantExit: 0,
+			check: func(t *testing.T, stdout string) {
+				expected := "category,name,price,tags\n" +
+					`furniture,desk,299.99,"[""office"",""wooden""]"` + "\n" +
+					`furniture,chair,150,"[""office"",""comfortable""]"` + "\n"
+				if stdout != expected {
+					t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout)
+				}
+			},
+		},
+		{
+			name:     "tsv_format_with_select",
+			args:     []string{"-output", "tsv", "-select", "name,price", testFile, "category:eq:furniture"},
+			wantExit: 0,
+			check: func(t *testing.T, stdout string) {
+				expected := "name\tprice\ndesk\t299.99\nchair\t150\n"
+				if stdout != expected {
+					t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout)
+				}
+			},
+		},
+		{
+			name:     "table_format",
+			args:     []string{"-output", "table", "-group-by", "category", testFile},
+			wantExit: 0,
+			check: func(t *testing.T, stdout string) {
+				expected := "category     count\n" +
+					"electronics  3\n" +
+					"books        1\n" +
+					"furniture    2\n"
+				if stdout != expected {
+					t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout)
+				}
+			},
+		},
+		{
+			name:     "unknown_format",
+			args:     []string{"-output", "yaml", testFile},
+			wantExit: 1,
+			check: func(t *testing.T, stdout string) {
+				if stdout != "" {
+					t.Errorf("Expected no output, got %s", stdout)
+				}
+			},
+		},
 	}
 	
 	for _, tt := range tests {
@@ -168,6 +258,37 @@ func TestOutputFormats(t *testing.T) {
 	}
 }
 
+func TestOutputFollow(t *testing.T) {
+	selected := record{columns: []column{{name: "id", path: "id"}}, values: map[string]interface{}{"id": 1.0}}
+
+	// the columns of -select results are known, rows are written as they come
+	for format, expected := range map[string]string{"csv": "line,id\n3,1\n", "table": "line  id\n3     1\n"} {
+		var b bytes.Buffer
+		out, err := newWriter(format, &b, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := out.write(sourceRecord{Line: 3, Record: selected}); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected {
+			t.Errorf("%s: expected %q once written, got %q", format, expected, b.String())
+		}
+	}
+
+	// other results are sampled for their columns
+	var b bytes.Buffer
+	out, _ := newWriter("csv", &b, true)
+	out.write(map[string]interface{}{"id": 1.0})
+	if b.Len() != 0 {
+		t.Errorf("Expected sampled results to be held back, got %q", b.String())
+	}
+	out.close()
+	if b.String() != "id\n1\n" {
+		t.Errorf("Expected the sampled results once closed, got %q", b.String())
+	}
+}
+
 func TestSkipAndLimit(t *testing.T) {
 	testFile := createTestData(t)
 	defer os.Remove(testFile)
@@ -237,6 +358,18 @@ func TestErrorHandling(t *testing.T) {
 			wantExit:       1,
 			stderrContains: "no such file",
 		},
+		{
+			name:           "missing file before a filter",
+			args:           []string{"missingfile", "price > 100"},
+			wantExit:       1,
+			stderrContains: "no such file or directory, and missingfile isn't a valid filter either",
+		},
+		{
+			name:           "missing file before --",
+			args:           []string{"missingfile", "--", "price > 100"},
+			wantExit:       1,
+			stderrContains: "no such file",
+		},
 		{
 			name:           "invalid operator",
 			args:           []string{testFile, "price:invalid:100"},
@@ -319,6 +452,668 @@ func TestComplexFilters(t *testing.T) {
 	}
 }
 
+func TestNestedFields(t *testing.T) {
+	content := `{"id": 1, "user": {"address": {"city": "Paris"}}, "items": [{"sku": "A-1"}]}
+{"id": 2, "user": {"address": {"city": "Lyon"}}, "items": [{"sku": "B-2"}]}
+{"id": 3, "user": {"address": {"city": "Paris"}}, "items": [{"sku": "C-3"}]}
+`
+	file, err := os.CreateTemp("", "test-nested-*.jsonl")
+	if err != nil {
+		t.Fatal("Failed to create test file:", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+	file.Close()
+
+	tests := []struct {
+		name        string
+		args        []string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "nested object path",
+			args:        []string{file.Name(), "user.address.city:eq:Paris"},
+			contains:    []string{`"id":1`, `"id":3`},
+			notContains: []string{`"id":2`},
+		},
+		{
+			name:        "array index path",
+			args:        []string{file.Name(), "items.0.sku:eq:B-2"},
+			contains:    []string{`"id":2`},
+			notContains: []string{`"id":1`, `"id":3`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runCLI(tt.args...)
+
+			if exitCode != 0 {
+				t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr)
+			}
+
+			for _, want := range tt.contains {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("Expected output to contain %q, but it didn't. Output: %s", want, stdout)
+				}
+			}
+
+			for _, notWant := range tt.notContains {
+				if strings.Contains(stdout, notWant) {
+					t.Errorf("Expected output to not contain %q, but it did. Output: %s", notWant, stdout)
+				}
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	testFile := createTestData(t)
+	defer os.Remove(testFile)
+
+	cmd := exec.Command("./testfq", "-explain", "2", testFile, "category:eq:electronics", "price:lt:500")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v. Stderr: %s", err, stderr.String())
+	}
+
+	if !strings.Contains(string(stdout), "headphones") {
+		t.Errorf("Expected matching records on stdout, got: %s", stdout)
+	}
+
+	explained := stderr.String()
+	for _, want := range []string{
+		"Record 1 did not match:",
+		`✗ price: lt 500 (value: 999.99)`,
+		"Record 2 did not match:",
+		`✗ category: eq "electronics" (value: "books")`,
+	} {
+		if !strings.Contains(explained, want) {
+			t.Errorf("Expected stderr to contain %q, got: %s", want, explained)
+		}
+	}
+	if strings.Contains(explained, "Record 3") {
+		t.Errorf("Expected only 2 explanations, got: %s", explained)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	testFile := createTestData(t)
+	defer os.Remove(testFile)
+
+	stdout, stderr, exitCode := runCLI("-select", "name,cost=price,tags.0,missing", testFile, "category:eq:furniture")
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr)
+	}
+
+	expected := `{"name":"desk","cost":299.99,"tags.0":"office","missing":null}
+{"name":"chair","cost":150,"tags.0":"office","missing":null}
+`
+	if stdout != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	_, stderr, exitCode = runCLI("-select", "name,,price", testFile)
+	if exitCode != 1 || !strings.Contains(stderr, "invalid field") {
+		t.Errorf("Expected an invalid field error, got exit code %d and stderr: %s", exitCode, stderr)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	testFile := createTestData(t)
+	defer os.Remove(testFile)
+
+	stdout, stderr, exitCode := runCLI("-group-by", "category", "-agg", "count,total=sum:price,max:name", testFile, "price:lt:900")
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr)
+	}
+
+	expected := `{"category":"books","count":1,"total":29.99,"max_name":"book"}
+{"category":"electronics","count":2,"total":899.98,"max_name":"smartphone"}
+{"category":"furniture","count":2,"total":449.99,"max_name":"desk"}
+`
+	if stdout != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	stdout, _, _ = runCLI("-group-by", "category", testFile)
+	if !strings.Contains(stdout, `{"category":"electronics","count":3}`) {
+		t.Errorf("Expected a count per category by default, got: %s", stdout)
+	}
+
+	for _, args := range [][]string{
+		{"-agg", "count", testFile},
+		{"-group-by", "category", "-agg", "median:price", testFile},
+		{"-group-by", "category", "-agg", "sum:price,sum:price", testFile},
+	} {
+		if _, stderr, exitCode := runCLI(args...); exitCode != 1 || !strings.Contains(stderr, "Error parsing aggregation") {
+			t.Errorf("Expected an aggregation error for %v, got exit code %d and stderr: %s", args, exitCode, stderr)
+		}
+	}
+}
+
+func TestJSONQuery(t *testing.T) {
+	testFile := createTestData(t)
+	defer os.Remove(testFile)
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantExit    int
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "json query",
+			args:        []string{"-q", `{"price": {"$lt": 500}, "tags": {"$hasItem": "office"}}`, testFile},
+			wantExit:    0,
+			contains:    []string{"desk", "chair"},
+			notContains: []string{"laptop", "book", "headphones"},
+		},
+		{
+			name:        "combined with filters",
+			args:        []string{"-q", `{"$or": [{"category": "furniture"}, {"name": "book"}]}`, testFile, "price:lt:200"},
+			wantExit:    0,
+			contains:    []string{"book", "chair"},
+			notContains: []string{"desk", "laptop"},
+		},
+		{
+			name:     "invalid json query",
+			args:     []string{"-q", `{"price": {"$between": [1, 2]}}`, testFile},
+			wantExit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runCLI(tt.args...)
+			if exitCode != tt.wantExit {
+				t.Fatalf("Expected exit code %d, got %d. Stderr: %s", tt.wantExit, exitCode, stderr)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("Expected output to contain %q, but it didn't. Output: %s", want, stdout)
+				}
+			}
+			for _, notWant := range tt.notContains {
+				if strings.Contains(stdout, notWant) {
+					t.Errorf("Expected output to not contain %q, but it did. Output: %s", notWant, stdout)
+				}
+			}
+			if tt.wantExit != 0 && !strings.Contains(stderr, "unknown operator $between") {
+				t.Errorf("Expected the parse error on stderr, got: %s", stderr)
+			}
+		})
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	testFile := createTestData(t)
+	defer os.Remove(testFile)
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantExit    int
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "or with parentheses",
+			args:        []string{testFile, `(category = "books" or category = "furniture") and price < 200 and not tags has "wooden"`},
+			wantExit:    0,
+			contains:    []string{"book", "chair"},
+			notContains: []string{"desk", "laptop", "headphones"},
+		},
+		{
+			name:        "combined with filters",
+			args:        []string{testFile, "category:eq:electronics", `price < 300 or name = "laptop"`},
+			wantExit:    0,
+			contains:    []string{"laptop", "headphones"},
+			notContains: []string{"smartphone", "chair"},
+		},
+		{
+			name:        "filters after --",
+			args:        []string{testFile, "--", "price:lt:100", "category = \"books\""},
+			wantExit:    0,
+			contains:    []string{"book"},
+			notContains: []string{"chair", "laptop"},
+		},
+		{
+			name:        "repeated filter fields",
+			args:        []string{testFile, "price:gt:100", "price:lt:250"},
+			wantExit:    0,
+			contains:    []string{"headphones", "chair"},
+			notContains: []string{"book", "desk", "laptop"},
+		},
+		{
+			name:     "syntax error",
+			args:     []string{testFile, `price < 500 and (category = electronics`},
+			wantExit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runCLI(tt.args...)
+			if exitCode != tt.wantExit {
+				t.Fatalf("Expected exit code %d, got %d. Stderr: %s", tt.wantExit, exitCode, stderr)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("Expected output to contain %q, but it didn't. Output: %s", want, stdout)
+				}
+			}
+			for _, notWant := range tt.notContains {
+				if strings.Contains(stdout, notWant) {
+					t.Errorf("Expected output to not contain %q, but it did. Output: %s", notWant, stdout)
+				}
+			}
+			if tt.wantExit != 0 && !strings.Contains(stderr, "column 29: expected a value") {
+				t.Errorf("Expected the error column on stderr, got: %s", stderr)
+			}
+		})
+	}
+}
+
+func TestInputs(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.jsonl")
+	second := filepath.Join(dir, "second.jsonl")
+	if err := os.WriteFile(first, []byte("{\"id\": 1, \"level\": \"error\"}\n{\"id\": 2, \"level\": \"info\"}\n"), 0644); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+	if err := os.WriteFile(second, []byte("{\"id\": 3, \"level\": \"error\"}\n"), 0644); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+	stdin := "{\"id\": 4, \"level\": \"error\"}\n"
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("{\"id\": 6, \"level\": \"error\"}\n"))
+	w.Close()
+	compressed := filepath.Join(dir, "archive.jsonl.gz")
+	if err := os.WriteFile(compressed, gz.Bytes(), 0644); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantExit int
+		expected []string
+	}{
+		{
+			name:     "stdin",
+			args:     []string{`level = "error"`},
+			wantExit: 0,
+			expected: []string{`{"id":4,"level":"error"}`},
+		},
+		{
+			name:     "stdin without filters",
+			args:     []string{"-limit", "1"},
+			wantExit: 0,
+			expected: []string{`{"id":4,"level":"error"}`},
+		},
+		{
+			name:     "files in sequence",
+			args:     []string{second, first, "level:eq:error"},
+			wantExit: 0,
+			expected: []string{`{"id":3,"level":"error"}`, `{"id":1,"level":"error"}`},
+		},
+		{
+			name:     "dash reads stdin",
+			args:     []string{first, "-", `level = "error"`},
+			wantExit: 0,
+			expected: []string{`{"id":1,"level":"error"}`, `{"id":4,"level":"error"}`},
+		},
+		{
+			name:     "skip and limit across files",
+			args:     []string{"-skip", "1", "-limit", "2", first, second},
+			wantExit: 0,
+			expected: []string{`{"id":2,"level":"info"}`, `{"id":3,"level":"error"}`},
+		},
+		{
+			name:     "glob with filename",
+			args:     []string{"-with-filename", filepath.Join(dir, "*.jsonl"), "-", `level = "error"`},
+			wantExit: 0,
+			expected: []string{
+				`{"file":"` + first + `","record":{"id":1,"level":"error"}}`,
+				`{"file":"` + second + `","record":{"id":3,"level":"error"}}`,
+				`{"file":"-","record":{"id":4,"level":"error"}}`,
+			},
+		},
+		{
+			name:     "lines over 64KB",
+			args:     []string{"-select", "id"},
+			stdin:    `{"id": 5, "payload": "` + strings.Repeat("x", 100*1024) + `"}`,
+			wantExit: 0,
+			expected: []string{`{"id":5}`},
+		},
+		{
+			name:     "gzip file",
+			args:     []string{compressed, first, `level = "error"`},
+			wantExit: 0,
+			expected: []string{`{"id":6,"level":"error"}`, `{"id":1,"level":"error"}`},
+		},
+		{
+			name:     "gzip stdin",
+			args:     []string{`level = "error"`},
+			stdin:    gz.String(),
+			wantExit: 0,
+			expected: []string{`{"id":6,"level":"error"}`},
+		},
+		{
+			name:     "glob without matches",
+			args:     []string{filepath.Join(dir, "*.csv"), `level = "error"`},
+			wantExit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := stdin
+			if tt.stdin != "" {
+				input = tt.stdin
+			}
+			stdout, stderr, exitCode := runCLIWithStdin(input, tt.args...)
+			if exitCode != tt.wantExit {
+				t.Fatalf("Expected exit code %d, got %d. Stderr: %s", tt.wantExit, exitCode, stderr)
+			}
+			if tt.wantExit != 0 {
+				if !strings.Contains(stderr, "no files match") {
+					t.Errorf("Expected a glob error, got: %s", stderr)
+				}
+				return
+			}
+
+			lines := strings.Split(strings.TrimSpace(stdout), "\n")
+			if strings.Join(lines, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(tt.expected, "\n"), stdout)
+			}
+		})
+	}
+}
+
+func TestCSVInput(t *testing.T) {
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "products.csv")
+	if err := os.WriteFile(csvFile, []byte("name,price,in_stock\nlaptop,999.99,true\n\"desk, oak\",299.99,false\nchair,150,true\n"), 0644); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantExit int
+		expected []string
+	}{
+		{
+			name:     "csv",
+			args:     []string{"-input", "csv", csvFile, "price < 500"},
+			wantExit: 0,
+			expected: []string{`{"in_stock":false,"name":"desk, oak","price":299.99}`, `{"in_stock":true,"name":"chair","price":150}`},
+		},
+		{
+			name:     "tsv from stdin",
+			args:     []string{"-input", "tsv", "-select", "name", "in_stock = true"},
+			stdin:    "name\tin_stock\nlamp\ttrue\nrug\tfalse\n",
+			wantExit: 0,
+			expected: []string{`{"name":"lamp"}`},
+		},
+		{
+			name:     "unknown format",
+			args:     []string{"-input", "xml", csvFile},
+			wantExit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runCLIWithStdin(tt.stdin, tt.args...)
+			if exitCode != tt.wantExit {
+				t.Fatalf("Expected exit code %d, got %d. Stderr: %s", tt.wantExit, exitCode, stderr)
+			}
+			if tt.wantExit != 0 {
+				if !strings.Contains(stderr, `unknown input format "xml"`) {
+					t.Errorf("Expected a format error, got: %s", stderr)
+				}
+				return
+			}
+			if got := strings.TrimSpace(stdout); got != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(tt.expected, "\n"), got)
+			}
+		})
+	}
+}
+
+func TestJSONArrayInput(t *testing.T) {
+	dir := t.TempDir()
+	arrayFile := filepath.Join(dir, "products.json")
+	if err := os.WriteFile(arrayFile, []byte(`[
+  {"name": "laptop", "price": 999.99},
+  {"name": "chair", "price": 150}
+]`), 0644); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantExit int
+		expected string
+		stderr   string
+	}{
+		{
+			name:     "detected array",
+			args:     []string{arrayFile, "price < 500"},
+			wantExit: 0,
+			expected: `{"name":"chair","price":150}`,
+		},
+		{
+			name:     "nested array from stdin",
+			args:     []string{"-array-path", ".data.items", "-select", "name"},
+			stdin:    `{"data": {"count": 2, "items": [{"name": "lamp"}, {"name": "rug"}]}}`,
+			wantExit: 0,
+			expected: "{\"name\":\"lamp\"}\n{\"name\":\"rug\"}",
+		},
+		{
+			name:     "missing array",
+			args:     []string{"-array-path", ".data.rows", arrayFile},
+			wantExit: 1,
+			stderr:   "array path .data.rows: .data not found",
+		},
+		{
+			name:     "max element size",
+			args:     []string{"-max-line-size", "20"},
+			stdin:    `[{"name": "lamp"}, {"name": "a rug with a long name"}]`,
+			wantExit: 1,
+			expected: `{"name":"lamp"}`,
+			stderr:   "element 1: line too long (max 20 bytes)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runCLIWithStdin(tt.stdin, tt.args...)
+			if exitCode != tt.wantExit {
+				t.Fatalf("Expected exit code %d, got %d. Stderr: %s", tt.wantExit, exitCode, stderr)
+			}
+			if !strings.Contains(stderr, tt.stderr) {
+				t.Errorf("Expected stderr to contain %q, got: %s", tt.stderr, stderr)
+			}
+			if got := strings.TrimSpace(stdout); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.jsonl")
+	content := "{\"id\": 1, \"level\": \"error\"}\n\n{\"id\": 2, \"level\": \"info\"}\n{\"id\": 3, \"level\": \"error\"}\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+	arrayPath := filepath.Join(t.TempDir(), "app.json")
+	array := "[\n  {\"id\": 1, \"level\": \"error\"},\n  {\"id\": 2, \"level\": \"info\"}\n]\n"
+	if err := os.WriteFile(arrayPath, []byte(array), 0644); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantExit int
+		expected string
+	}{
+		{
+			name:     "line numbers",
+			args:     []string{"-n", path, `level = "error"`},
+			expected: "{\"line\":1,\"record\":{\"id\":1,\"level\":\"error\"}}\n{\"line\":4,\"record\":{\"id\":3,\"level\":\"error\"}}",
+		},
+		{
+			name:     "with filename",
+			args:     []string{"-n", "-with-filename", "-select", "id", path, "id = 2"},
+			expected: `{"file":"` + path + `","line":3,"record":{"id":2}}`,
+		},
+		{
+			name:     "stdin as csv",
+			args:     []string{"-n", "-output", "csv", "id > 1"},
+			stdin:    content,
+			expected: "line,id,level\n3,2,info\n4,3,error",
+		},
+		{
+			name:     "csv input",
+			args:     []string{"-n", "-input", "csv", path},
+			wantExit: 1,
+			expected: "-n requires JSONL input",
+		},
+		{
+			name:     "json array",
+			args:     []string{"-n", arrayPath, `level = "error"`},
+			wantExit: 1,
+			expected: "-n requires JSONL input, not a JSON array",
+		},
+		{
+			name:     "json array on stdin",
+			args:     []string{"-n"},
+			stdin:    array,
+			wantExit: 1,
+			expected: "-n requires JSONL input, not a JSON array",
+		},
+		{
+			name:     "group by",
+			args:     []string{"-n", "-group-by", "level", path},
+			wantExit: 1,
+			expected: "-n can't be used with -group-by",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runCLIWithStdin(tt.stdin, tt.args...)
+			if exitCode != tt.wantExit {
+				t.Fatalf("Expected exit code %d, got %d. Stderr: %s", tt.wantExit, exitCode, stderr)
+			}
+			if tt.wantExit != 0 {
+				if !strings.Contains(stderr, tt.expected) {
+					t.Errorf("Expected error %q, got: %s", tt.expected, stderr)
+				}
+				return
+			}
+			if got := strings.TrimSpace(stdout); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("{\"id\": 0, \"level\": \"error\"}\n"), 0644); err != nil {
+		t.Fatal("Failed to write test data:", err)
+	}
+
+	for name, args := range map[string][]string{
+		"stdin": {"-follow", `level = "error"`},
+		"csv":   {"-follow", "-input", "csv", path},
+	} {
+		_, stderr, exitCode := runCLI(args...)
+		if exitCode != 1 || !strings.Contains(stderr, "-follow requires") {
+			t.Errorf("%s: expected a -follow error, got exit code %d: %s", name, exitCode, stderr)
+		}
+	}
+
+	cmd := exec.Command("./testfq", "-n", "-follow", path, `level = "error"`)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal("Failed to get stdout:", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal("Failed to start CLI:", err)
+	}
+
+	// append until the CLI, which starts at the end of the file, outputs a line
+	done := make(chan struct{})
+	go func() {
+		for id := 1; ; id++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(file, "{\"id\": %d, \"level\": \"info\"}\n{\"id\": %d, \"level\": \"error\"}\n", id, id)
+			file.Close()
+		}
+	}()
+
+	lines := make(chan string)
+	go func() {
+		line, _ := bufio.NewReader(stdout).ReadString('\n')
+		lines <- line
+	}()
+
+	select {
+	case line := <-lines:
+		close(done)
+		// lines are numbered from the first line appended, an info line before each error line
+		var tagged struct {
+			Line   int
+			Record map[string]interface{}
+		}
+		if err := json.Unmarshal([]byte(line), &tagged); err != nil || tagged.Record["level"] != "error" || tagged.Record["id"] == 0.0 {
+			t.Errorf("Expected an appended error record, got %q", line)
+		} else if tagged.Line == 0 || tagged.Line%2 != 0 {
+			t.Errorf("Expected the even line number of an appended error record, got %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		close(done)
+		cmd.Process.Kill()
+		t.Fatal("Timed out waiting for a followed record")
+	}
+
+	cmd.Process.Signal(os.Interrupt)
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Expected an interrupted -follow to exit cleanly, got %v", err)
+	}
+}
+
 func TestHelpAndUsage(t *testing.T) {
 	tests := []struct {
 		name     string
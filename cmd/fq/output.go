@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// sampleRows is the number of rows whose keys are the columns of the csv, tsv and
+// table outputs without -select
+const sampleRows = 100
+
+// writer outputs results in an -output format
+type writer interface {
+	write(result interface{}) error
+	// close outputs what's left once all results are written
+	close() error
+}
+
+// newWriter returns the writer of the -output format to w, follow writing the rows of the
+// csv, tsv and table formats as they come (see columnsWriter)
+func newWriter(format string, w io.Writer, follow bool) (writer, error) {
+	switch format {
+	case "jsonl":
+		return &jsonlWriter{w: bufio.NewWriter(w)}, nil
+	case "json":
+		return &jsonWriter{w: bufio.NewWriter(w)}, nil
+	case "csv", "tsv":
+		out := csv.NewWriter(w)
+		if format == "tsv" {
+			out.Comma = '\t'
+		}
+		return &columnsWriter{out: csvRows{out}, follow: follow}, nil
+	case "table":
+		return &columnsWriter{out: tableRows{tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}, follow: follow}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, expected jsonl, json, csv, tsv or table", format)
+}
+
+// jsonlWriter outputs a JSON value per line
+type jsonlWriter struct {
+	w *bufio.Writer
+}
+
+func (j *jsonlWriter) write(result interface{}) error {
+	bytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	j.w.Write(bytes)
+	j.w.WriteByte('\n')
+	// flush as results come, fq is used in pipes
+	return j.w.Flush()
+}
+
+func (j *jsonlWriter) close() error {
+	return j.w.Flush()
+}
+
+// jsonWriter outputs a JSON array of the results, a line per result
+type jsonWriter struct {
+	w       *bufio.Writer
+	written bool
+}
+
+func (j *jsonWriter) write(result interface{}) error {
+	bytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if j.written {
+		j.w.WriteString(",\n")
+	} else {
+		j.w.WriteString("[\n")
+	}
+	j.written = true
+	j.w.Write(bytes)
+	return nil
+}
+
+func (j *jsonWriter) close() error {
+	if j.written {
+		j.w.WriteString("\n]\n")
+	} else {
+		j.w.WriteString("[]\n")
+	}
+	return j.w.Flush()
+}
+
+// rowWriter outputs rows of cells
+type rowWriter interface {
+	writeRow(cells []string) error
+	flush() error
+}
+
+// columnsWriter outputs the results as rows of cells. The columns are the union of the
+// fields of the first sampleRows results: later fields that aren't columns are dropped.
+// The columns of -select results are known from the first result, nothing is sampled.
+type columnsWriter struct {
+	out     rowWriter
+	columns []string
+	sample  []interface{}
+	// follow flushes each row once the columns are known, like jsonl
+	follow bool
+}
+
+func (c *columnsWriter) write(result interface{}) error {
+	var err error
+	switch {
+	case c.columns != nil:
+		err = c.writeResult(result)
+	case len(c.sample) == 0 && selected(result):
+		c.sample = append(c.sample, result)
+		err = c.writeSample()
+	default:
+		c.sample = append(c.sample, result)
+		if len(c.sample) < sampleRows {
+			return nil
+		}
+		err = c.writeSample()
+	}
+
+	if err == nil && c.follow && c.columns != nil {
+		err = c.out.flush()
+	}
+	return err
+}
+
+func (c *columnsWriter) close() error {
+	if c.columns == nil && len(c.sample) > 0 {
+		if err := c.writeSample(); err != nil {
+			return err
+		}
+	}
+	return c.out.flush()
+}
+
+// writeSample outputs the header, derived from the sampled results, and the sampled results
+func (c *columnsWriter) writeSample() error {
+	seen := map[string]bool{}
+	c.columns = []string{}
+	for _, result := range c.sample {
+		names, _ := fieldsOf(result)
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				c.columns = append(c.columns, name)
+			}
+		}
+	}
+
+	if err := c.out.writeRow(c.columns); err != nil {
+		return err
+	}
+	for _, result := range c.sample {
+		if err := c.writeResult(result); err != nil {
+			return err
+		}
+	}
+	c.sample = nil
+	return nil
+}
+
+func (c *columnsWriter) writeResult(result interface{}) error {
+	_, values := fieldsOf(result)
+	cells := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		value, err := cell(values[column])
+		if err != nil {
+			return err
+		}
+		cells[i] = value
+	}
+	return c.out.writeRow(cells)
+}
+
+// selected reports whether result is a -select record, tagged with its source or not
+func selected(result interface{}) bool {
+	switch r := result.(type) {
+	case record:
+		return true
+	case sourceRecord:
+		return selected(r.Record)
+	}
+	return false
+}
+
+// fieldsOf returns the field names of a result, in output order, and their values.
+// Results that aren't objects are a value field.
+func fieldsOf(result interface{}) ([]string, map[string]interface{}) {
+	switch r := result.(type) {
+	case record:
+		names := make([]string, len(r.columns))
+		for i, c := range r.columns {
+			names[i] = c.name
+		}
+		return names, r.values
+	case sourceRecord:
+		names, values := fieldsOf(r.Record)
+		var source []string
+		tagged := map[string]interface{}{}
+		if r.File != "" {
+			source = append(source, "file")
+			tagged["file"] = r.File
+		}
+		if r.Line != 0 {
+			source = append(source, "line")
+			tagged["line"] = r.Line
+		}
+		for name, value := range values {
+			tagged[name] = value
+		}
+		return append(source, names...), tagged
+	case map[string]interface{}:
+		names := make([]string, 0, len(r))
+		for name := range r {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, r
+	}
+	return []string{"value"}, map[string]interface{}{"value": result}
+}
+
+// cell formats a value: strings as is, null as an empty cell, others as JSON
+func cell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	bytes, err := json.Marshal(value)
+	return string(bytes), err
+}
+
+// csvRows writes CSV or TSV rows
+type csvRows struct {
+	w *csv.Writer
+}
+
+func (c csvRows) writeRow(cells []string) error {
+	return c.w.Write(cells)
+}
+
+func (c csvRows) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// tableRows writes rows aligned in columns, buffered until flushed
+type tableRows struct {
+	w *tabwriter.Writer
+}
+
+// tableCell keeps cells on a line and in their column
+var tableCell = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+func (t tableRows) writeRow(cells []string) error {
+	for i, c := range cells {
+		cells[i] = tableCell.Replace(c)
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+	return err
+}
+
+func (t tableRows) flush() error {
+	return t.w.Flush()
+}
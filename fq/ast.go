@@ -0,0 +1,459 @@
+package fq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Op is the inspectable form of a query (see Inspect)
+type Op struct {
+	// Name is the operator name ("eq", "gt", "and", "strict", ...), "q" for map queries,
+	// "value" for plain values, "nil" for nil and "custom" for custom predicates
+	Name     string
+	Args     []interface{}  // operator arguments, the value itself for "value"
+	Fields   map[string]*Op // fields of a "q"
+	Children []*Op          // queries combined by "and", "or", "not" and "strict"
+}
+
+// ErrCustomPredicate is returned when rebuilding a query holding custom predicates,
+// which are opaque and only recorded as "custom" nodes
+var ErrCustomPredicate = errors.New("custom predicates can't be rebuilt")
+
+// Inspect returns the AST of query. Queries built with the fq operators are fully described,
+// custom predicates are "custom" nodes without arguments.
+func Inspect(query Query) *Op {
+	switch q := query.(type) {
+	case Q:
+		return inspectMapQuery(q)
+	case map[string]interface{}:
+		return inspectMapQuery(q)
+	case nil:
+		return &Op{Name: "nil"}
+	}
+
+	if _, ok := query.(P); !ok {
+		if _, ok := query.(func(interface{}) bool); !ok {
+			return &Op{Name: "value", Args: []interface{}{query}}
+		}
+	}
+
+	op := describe(query)
+	if op == nil {
+		return &Op{Name: "custom"}
+	}
+
+	node := &Op{Name: op.name, Args: op.args}
+	for _, child := range op.children {
+		node.Children = append(node.Children, Inspect(child))
+	}
+	return node
+}
+
+func inspectMapQuery(query Q) *Op {
+	node := &Op{Name: "q", Fields: make(map[string]*Op, len(query))}
+	for key, condition := range query {
+		node.Fields[key] = Inspect(condition)
+	}
+	return node
+}
+
+// Query rebuilds the query described by op.
+// It fails with ErrCustomPredicate for "custom" nodes, *ErrUnknownOperator for unknown names
+// and *ErrInvalidOperand for invalid arguments.
+func (op *Op) Query() (Query, error) {
+	query, err := op.build("")
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(query, nil); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func (op *Op) build(path string) (Query, error) {
+	invalid := func(reason string) error {
+		return &ErrInvalidOperand{Path: path, Op: op.Name, Operand: op.Args, Reason: reason}
+	}
+
+	children := make([]Query, len(op.Children))
+	for i, child := range op.Children {
+		if child == nil {
+			return nil, invalid(fmt.Sprintf("child %d is null", i))
+		}
+		query, err := child.build(path)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = query
+	}
+
+	arity := map[string]int{
+		"value": 1, "eq": 1, "gt": 1, "gte": 1, "lt": 1, "lte": 1,
+		"contains": 1, "hasitem": 1, "match": 1, "geowithin": 3,
+	}
+	if n, ok := arity[op.Name]; ok && len(op.Args) != n {
+		return nil, invalid(fmt.Sprintf("expected %d arguments", n))
+	}
+	if (op.Name == "not" || op.Name == "strict") && len(children) != 1 {
+		return nil, invalid("expected 1 child")
+	}
+
+	switch op.Name {
+	case "q":
+		query := make(Q, len(op.Fields))
+		for key, field := range op.Fields {
+			if field == nil {
+				return nil, &ErrInvalidOperand{Path: joinPath(path, key), Op: op.Name, Reason: "null condition"}
+			}
+			condition, err := field.build(joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			query[key] = condition
+		}
+		return query, nil
+	case "nil":
+		return nil, nil
+	case "value":
+		return op.Args[0], nil
+	case "custom":
+		return nil, fmt.Errorf("%s: %w", describePath(path), ErrCustomPredicate)
+
+	case "eq":
+		return Eq(op.Args[0]), nil
+	case "gt":
+		return Gt(op.Args[0]), nil
+	case "gte":
+		return Gte(op.Args[0]), nil
+	case "lt":
+		return Lt(op.Args[0]), nil
+	case "lte":
+		return Lte(op.Args[0]), nil
+	case "in":
+		return In(op.Args...), nil
+	case "hasitem":
+		return HasItem(op.Args[0]), nil
+	case "match":
+		return Match(op.Args[0]), nil
+	case "containsall":
+		return ContainsAll(op.Args...), nil
+	case "containsany":
+		return ContainsAny(op.Args...), nil
+	case "contains":
+		s, ok := op.Args[0].(string)
+		if !ok {
+			return nil, invalid("not a string")
+		}
+		return Contains(s), nil
+	case "geowithin":
+		var coords [3]float64
+		for i, arg := range op.Args {
+			n, ok := arg.(float64)
+			if !ok {
+				return nil, invalid("not 3 float64 arguments")
+			}
+			coords[i] = n
+		}
+		return GeoWithin(coords[0], coords[1], coords[2]), nil
+
+	case "and":
+		return And(children...), nil
+	case "or":
+		return Or(children...), nil
+	case "not":
+		return Not(children[0]), nil
+	case "strict":
+		return Strict(children[0]), nil
+
+	default:
+		return nil, &ErrUnknownOperator{Path: path, Op: op.Name}
+	}
+}
+
+// MarshalQuery encodes query as JSON (see Inspect and Op.MarshalJSON)
+func MarshalQuery(query Query) ([]byte, error) {
+	return json.Marshal(Inspect(query))
+}
+
+// UnmarshalQuery decodes a query encoded by MarshalQuery (see Op.Query)
+func UnmarshalQuery(data []byte) (Query, error) {
+	var op Op
+	if err := json.Unmarshal(data, &op); err != nil {
+		return nil, err
+	}
+	return op.Query()
+}
+
+// opJSON is the JSON form of an Op
+type opJSON struct {
+	Op       string         `json:"op"`
+	Args     []interface{}  `json:"args,omitempty"`
+	Fields   map[string]*Op `json:"fields,omitempty"`
+	Children []*Op          `json:"children,omitempty"`
+}
+
+// MarshalJSON encodes op as {"op": name, "args": [...], "fields": {...}, "children": [...]}.
+// Strings, booleans, float64 values, nil and []interface{} are encoded as JSON values, other
+// values as {"type": name, "value": ...} to decode to the same Go type: ints and uints of any size,
+// float32, time.Time, *regexp.Regexp, map[string]interface{} and slices of those.
+func (op *Op) MarshalJSON() ([]byte, error) {
+	args := make([]interface{}, len(op.Args))
+	for i, arg := range op.Args {
+		encoded, err := encodeValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op.Name, err)
+		}
+		args[i] = encoded
+	}
+	return json.Marshal(opJSON{Op: op.Name, Args: args, Fields: op.Fields, Children: op.Children})
+}
+
+// UnmarshalJSON decodes an op encoded by MarshalJSON
+func (op *Op) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Op       string            `json:"op"`
+		Args     []json.RawMessage `json:"args"`
+		Fields   map[string]*Op    `json:"fields"`
+		Children []*Op             `json:"children"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Op == "" {
+		return errors.New(`missing "op"`)
+	}
+
+	*op = Op{Name: raw.Op, Fields: raw.Fields, Children: raw.Children}
+	for _, arg := range raw.Args {
+		dec := json.NewDecoder(bytes.NewReader(arg))
+		dec.UseNumber()
+
+		var encoded interface{}
+		if err := dec.Decode(&encoded); err != nil {
+			return err
+		}
+		value, err := decodeValue(encoded)
+		if err != nil {
+			return fmt.Errorf("%s: %w", raw.Op, err)
+		}
+		op.Args = append(op.Args, value)
+	}
+	return nil
+}
+
+// valueTypes are the types encoded as {"type": name, "value": ...}, slices of them are named "[]" + name
+var valueTypes = map[string]reflect.Type{
+	"int":     reflect.TypeOf(int(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+	"string":  stringType,
+	"bool":    reflect.TypeOf(false),
+	"time":    timeType,
+	"regexp":  reflect.TypeOf((*regexp.Regexp)(nil)),
+	"map":     reflect.TypeOf(map[string]interface{}(nil)),
+	"any":     anyType,
+}
+
+// typeName returns the name of t in valueTypes
+func typeName(t reflect.Type) (string, bool) {
+	for name, vt := range valueTypes {
+		if vt == t {
+			return name, true
+		}
+	}
+	if t.Kind() == reflect.Slice {
+		if name, ok := typeName(t.Elem()); ok {
+			return "[]" + name, true
+		}
+	}
+	return "", false
+}
+
+func encodeValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil, string, bool, float64:
+		return val, nil
+	case time.Time:
+		return map[string]interface{}{"type": "time", "value": val.Format(time.RFC3339Nano)}, nil
+	case *regexp.Regexp:
+		return map[string]interface{}{"type": "regexp", "value": val.String()}, nil
+	case map[string]interface{}:
+		fields := make(map[string]interface{}, len(val))
+		for key, field := range val {
+			encoded, err := encodeValue(field)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = encoded
+		}
+		return map[string]interface{}{"type": "map", "value": fields}, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	name, ok := typeName(rv.Type())
+	if !ok {
+		return nil, fmt.Errorf("can't encode value %v of type %T", v, v)
+	}
+
+	if rv.Kind() == reflect.Slice {
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			encoded, err := encodeValue(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items[i] = encoded
+		}
+		if name == "[]any" {
+			return items, nil
+		}
+		return map[string]interface{}{"type": name, "value": items}, nil
+	}
+
+	if isIntKind(rv.Kind()) {
+		return map[string]interface{}{"type": name, "value": json.Number(strconv.FormatInt(rv.Int(), 10))}, nil
+	}
+	if isUintKind(rv.Kind()) {
+		return map[string]interface{}{"type": name, "value": json.Number(strconv.FormatUint(rv.Uint(), 10))}, nil
+	}
+	// float32
+	return map[string]interface{}{"type": name, "value": rv.Float()}, nil
+}
+
+// decodeValue decodes a value encoded by encodeValue, numbers being json.Number
+func decodeValue(encoded interface{}) (interface{}, error) {
+	switch val := encoded.(type) {
+	case nil, string, bool:
+		return val, nil
+	case json.Number:
+		return val.Float64()
+	case []interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			decoded, err := decodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = decoded
+		}
+		return items, nil
+	}
+
+	tagged, ok := encoded.(map[string]interface{})
+	name, _ := tagged["type"].(string)
+	t, known := valueTypes[name]
+	if len(name) > 2 && name[:2] == "[]" {
+		if elem, ok := valueTypes[name[2:]]; ok {
+			t, known = reflect.SliceOf(elem), true
+		}
+	}
+	if !ok || !known {
+		return nil, fmt.Errorf("invalid encoded value %v", encoded)
+	}
+	value := tagged["value"]
+
+	invalid := func(err error) error {
+		if err == nil {
+			err = fmt.Errorf("unexpected %T", value)
+		}
+		return fmt.Errorf("invalid %s value %v: %w", name, value, err)
+	}
+
+	switch {
+	case t == timeType:
+		s, _ := value.(string)
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return parsed, nil
+
+	case t == valueTypes["regexp"]:
+		s, ok := value.(string)
+		if !ok {
+			return nil, invalid(nil)
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return re, nil
+
+	case t == valueTypes["map"]:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, invalid(nil)
+		}
+		decoded := make(map[string]interface{}, len(fields))
+		for key, field := range fields {
+			d, err := decodeValue(field)
+			if err != nil {
+				return nil, err
+			}
+			decoded[key] = d
+		}
+		return decoded, nil
+
+	case t.Kind() == reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, invalid(nil)
+		}
+		slice := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			d, err := decodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			rv := reflect.ValueOf(d)
+			if d == nil || !rv.Type().AssignableTo(t.Elem()) {
+				return nil, invalid(fmt.Errorf("item %v isn't a %s", d, t.Elem()))
+			}
+			slice.Index(i).Set(rv)
+		}
+		return slice.Interface(), nil
+	}
+
+	number, ok := value.(json.Number)
+	if !ok {
+		return nil, invalid(nil)
+	}
+	rv := reflect.New(t).Elem()
+	switch {
+	case isIntKind(t.Kind()):
+		n, err := strconv.ParseInt(number.String(), 10, t.Bits())
+		if err != nil {
+			return nil, invalid(err)
+		}
+		rv.SetInt(n)
+	case isUintKind(t.Kind()):
+		n, err := strconv.ParseUint(number.String(), 10, t.Bits())
+		if err != nil {
+			return nil, invalid(err)
+		}
+		rv.SetUint(n)
+	default:
+		n, err := number.Float64()
+		if err != nil {
+			return nil, invalid(err)
+		}
+		rv.SetFloat(n)
+	}
+	return rv.Interface(), nil
+}
@@ -0,0 +1,162 @@
+package fq
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	op := Inspect(Q{
+		"Price": And(Gt(10), Lt(100.5)),
+		"Name":  "x",
+		"Tags":  func(v interface{}) bool { return true },
+		"Meta":  nil,
+	})
+
+	if op.Name != "q" || len(op.Fields) != 4 {
+		t.Fatalf("Expected a q with 4 fields, got %+v", op)
+	}
+
+	price := op.Fields["Price"]
+	if price.Name != "and" || len(price.Children) != 2 || price.Children[0].Name != "gt" || price.Children[0].Args[0] != 10 {
+		t.Errorf("Unexpected Price node %+v", price)
+	}
+	if name := op.Fields["Name"]; name.Name != "value" || name.Args[0] != "x" {
+		t.Errorf("Unexpected Name node %+v", name)
+	}
+	if tags := op.Fields["Tags"]; tags.Name != "custom" || tags.Args != nil {
+		t.Errorf("Expected an opaque custom node, got %+v", tags)
+	}
+	if meta := op.Fields["Meta"]; meta.Name != "nil" {
+		t.Errorf("Unexpected Meta node %+v", meta)
+	}
+
+	// custom predicates are never called to be described
+	calls := 0
+	custom := P(func(v interface{}) bool {
+		calls++
+		return true
+	})
+	if node := Inspect(Or(custom, Not(custom))); node.Name != "or" || node.Children[0].Name != "custom" || node.Children[1].Children[0].Name != "custom" || calls != 0 {
+		t.Errorf("Expected custom nodes without calls, got %+v after %d calls", node, calls)
+	}
+
+	// wherever the operator closures are built
+	for _, query := range []Query{Eq(1), In(1, 2), Strict(Gt(1)), strictComparison("gt", 1, "")} {
+		if node := Inspect(query); node.Name == "custom" {
+			t.Errorf("Expected %v to be described, got a custom node", query)
+		}
+	}
+}
+
+func TestMarshalQuery(t *testing.T) {
+	baseTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	queries := map[string]Query{
+		"plain values": Q{"ID": 3, "Name": "Laptop Pro", "InStock": true, "Rating": 4.7, "Properties.material": nil},
+		"comparisons":  Q{"Price": And(Gte(int64(100)), Lt(float32(1000))), "Stock": Gt(uint8(40))},
+		"time":         Q{"CreatedAt": Gt(baseTime.AddDate(0, 2, 0))},
+		"collections":  Q{"Tags": ContainsAll("premium", "work"), "Categories": ContainsAny("audio", "luxury"), "ID": In(1, 2, 3)},
+		"strings":      Q{"Name": Or(Contains("Tablet"), Match("WATCH"), Match(regexp.MustCompile(`^Lap`)))},
+		"items":        Q{"Tags": HasItem("premium")},
+		"typed slices": Q{"Tags": Eq([]string{"premium", "work", "professional"})},
+		"nested":       Q{"Manufacturer": Q{"Country": Not(In("USA", "China"))}},
+		"strict":       Strict(Q{"Price": Lt(500)}),
+		"geo":          Q{"Properties.location": Not(GeoWithin(1, 2, 3))},
+		"map value":    Q{"Properties": Eq(map[string]interface{}{"a": []interface{}{1, "b"}})},
+		"nil":          nil,
+	}
+
+	products := getTestProducts()
+
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			data, err := MarshalQuery(query)
+			if err != nil {
+				t.Fatalf("Unexpected marshal error: %v", err)
+			}
+
+			decoded, err := UnmarshalQuery(data)
+			if err != nil {
+				t.Fatalf("Unexpected unmarshal error: %v\n%s", err, data)
+			}
+
+			if !reflect.DeepEqual(Inspect(decoded), Inspect(query)) {
+				t.Errorf("Expected the same AST after a round trip of %s", data)
+			}
+
+			again, err := MarshalQuery(decoded)
+			if err != nil || string(again) != string(data) {
+				t.Errorf("Expected the same JSON after a round trip, got %s and %s (%v)", data, again, err)
+			}
+
+			expected, err := Filter(products, query, 0, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result, err := Filter(products, decoded, 0, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !equalInts(productIDs(result), productIDs(expected)) {
+				t.Errorf("Expected %v, got %v", productIDs(expected), productIDs(result))
+			}
+		})
+	}
+
+	data, err := MarshalQuery(Q{"Price": Gt(3)})
+	if err != nil || string(data) != `{"op":"q","fields":{"Price":{"op":"gt","args":[{"type":"int","value":3}]}}}` {
+		t.Errorf("Unexpected encoding %s (%v)", data, err)
+	}
+}
+
+func TestMarshalQueryErrors(t *testing.T) {
+	type Status string
+
+	custom, err := MarshalQuery(Q{"ID": func(v interface{}) bool { return true }})
+	if err != nil {
+		t.Fatalf("Expected custom predicates to marshal, got %v", err)
+	}
+	if _, err := UnmarshalQuery(custom); !errors.Is(err, ErrCustomPredicate) {
+		t.Errorf("Expected ErrCustomPredicate, got %v", err)
+	}
+
+	if _, err := MarshalQuery(Q{"Status": Status("active")}); err == nil || !strings.Contains(err.Error(), "can't encode") {
+		t.Errorf("Expected an encoding error for a named type, got %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		data           string
+		errorSubstring string
+	}{
+		{"invalid json", `{"op":`, "unexpected end"},
+		{"missing op", `{"args":[1]}`, `missing "op"`},
+		{"unknown op", `{"op":"q","fields":{"a":{"op":"between","args":[1,2]}}}`, `field "a": unknown operator between`},
+		{"arity", `{"op":"gt","args":[1,2]}`, "expected 1 arguments"},
+		{"children", `{"op":"not"}`, "expected 1 child"},
+		{"null child", `{"op":"and","children":[null]}`, "child 0 is null"},
+		{"null field", `{"op":"q","fields":{"a":null}}`, `field "a": q: invalid operand <nil>: null condition`},
+		{"bad type", `{"op":"eq","args":[{"type":"complex","value":1}]}`, "invalid encoded value"},
+		{"bad int", `{"op":"eq","args":[{"type":"int8","value":300}]}`, "invalid int8 value 300"},
+		{"bad time", `{"op":"gt","args":[{"type":"time","value":"yesterday"}]}`, "invalid time value"},
+		{"bad slice item", `{"op":"eq","args":[{"type":"[]string","value":["a",1]}]}`, "isn't a string"},
+		{"invalid operand", `{"op":"geowithin","args":[1,2,-3]}`, "negative radius"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := UnmarshalQuery([]byte(tc.data))
+			if err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+			if !strings.Contains(err.Error(), tc.errorSubstring) {
+				t.Errorf("Expected error containing %q, got: %v", tc.errorSubstring, err)
+			}
+		})
+	}
+}
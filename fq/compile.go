@@ -0,0 +1,566 @@
+package fq
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Compiled is a query prepared for items of type T.
+// Field lookups are resolved against T once, instead of for every evaluated item.
+type Compiled[T any] struct {
+	query Query
+	match matcher
+}
+
+// matcher evaluates a compiled query against a value, an invalid value stands for nil
+type matcher func(reflect.Value) bool
+
+// accessor resolves a compiled field path against a value
+type accessor func(reflect.Value) reflect.Value
+
+// Compile prepares query for items of type T.
+// It fails with the same errors as Validate. Only WithTagNames applies to compilation,
+// the options of the filtering calls are passed to them.
+func Compile[T any](query Query, opts ...Option) (*Compiled[T], error) {
+	if query == nil {
+		// like Filter, a nil query matches everything
+		return &Compiled[T]{match: func(reflect.Value) bool { return true }}, nil
+	}
+
+	m, err := compileItemQuery(query, typeOf[T](), newOptions(opts).tagNames)
+	if err != nil {
+		return nil, err
+	}
+	return &Compiled[T]{query: query, match: m}, nil
+}
+
+// Query returns the query the Compiled was built from
+func (c *Compiled[T]) Query() Query {
+	return c.query
+}
+
+// Match checks if item satisfies the compiled query. An item the query can't be
+// evaluated against, like one failing a Strict comparison, doesn't match (see MatchErr).
+func (c *Compiled[T]) Match(item T) bool {
+	matched, _ := c.MatchErr(item)
+	return matched
+}
+
+// MatchErr checks if item satisfies the compiled query like Match, returning the error
+// evaluating it, like the *ErrTypeMismatch of a Strict comparison, along with false
+func (c *Compiled[T]) MatchErr(item T) (matched bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(evalError)
+			if !ok {
+				panic(r)
+			}
+			matched, err = false, e.err
+		}
+	}()
+	return c.matches(item), nil
+}
+
+// matches evaluates the compiled query against item, evaluation errors being
+// left for the filtering functions to recover (see evalError)
+func (c *Compiled[T]) matches(item T) bool {
+	return c.match(reflect.ValueOf(&item).Elem())
+}
+
+// Filter filters data with the compiled query (see Filter)
+func (c *Compiled[T]) Filter(data []T, skip int, limit int, opts ...Option) ([]T, error) {
+	values := reflect.ValueOf(data)
+	return filterSlice(context.Background(), data, func(i int) bool {
+		return c.match(values.Index(i))
+	}, skip, limit, newObserver[T](newOptions(opts)))
+}
+
+// FilterC filters data with the compiled query (see FilterC)
+func (c *Compiled[T]) FilterC(input <-chan T, skip int, limit int, opts ...Option) (<-chan T, <-chan error) {
+	return filterChan(context.Background(), input, c.matches, skip, limit, newObserver[T](newOptions(opts)))
+}
+
+// FilterSeq filters data with the compiled query (see FilterSeq)
+func (c *Compiled[T]) FilterSeq(seq iter.Seq[T], skip int, limit int, opts ...Option) iter.Seq[T] {
+	return withoutErrors(c.FilterSeq2(withErrors(seq), skip, limit, opts...))
+}
+
+// FilterSeq2 filters data with the compiled query (see FilterSeq2)
+func (c *Compiled[T]) FilterSeq2(seq iter.Seq2[T, error], skip int, limit int, opts ...Option) iter.Seq2[T, error] {
+	return filterSeq(seq, c.matches, skip, limit, newOptions(opts))
+}
+
+// compileMatch compiles query for items of type T like Compile, the filtering functions
+// evaluate items with the returned function
+func compileMatch[T any](query Query, o *options) (func(T) bool, error) {
+	m, err := compileItemQuery(query, typeOf[T](), o.tagNames)
+	if err != nil {
+		return nil, err
+	}
+	return func(item T) bool {
+		return m(reflect.ValueOf(&item).Elem())
+	}, nil
+}
+
+// typeOf returns the type T, an interface type included
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// compileItemQuery builds the matcher of query for items of type t, Records being matched by their
+// Value. Struct fields are resolved by tag names too with tagNames (see WithTagNames).
+func compileItemQuery(query Query, t reflect.Type, tagNames bool) (matcher, error) {
+	c := compiler{tagNames: tagNames}
+	switch {
+	case t == recordType:
+		m, err := c.query(query, anyType, "")
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) bool {
+			// Value is the first field
+			return m(v.Field(0))
+		}, nil
+
+	case t == recordPtrType:
+		m, err := c.query(query, anyType, "")
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) bool {
+			if v.IsNil() {
+				return m(reflect.Value{})
+			}
+			return m(v.Elem().Field(0))
+		}, nil
+
+	case t.Kind() == reflect.Interface:
+		m, err := c.query(query, t, "")
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) bool {
+			switch item := toInterface(v).(type) {
+			case Record, *Record:
+				value := recordValue(item)
+				return m(reflect.ValueOf(&value).Elem())
+			}
+			return m(v)
+		}, nil
+	}
+	return c.query(query, t, "")
+}
+
+// compiler holds the settings queries are compiled with
+type compiler struct {
+	tagNames bool
+}
+
+// query builds the matcher of query for values of type t, path is the field path
+// of those values and only used for error messages
+func (c compiler) query(query Query, t reflect.Type, path string) (matcher, error) {
+	switch q := query.(type) {
+	case P:
+		return c.predicate(q, t, path)
+	case func(interface{}) bool:
+		return c.predicate(q, t, path)
+	case Q:
+		return c.mapQuery(q, t, path)
+	case map[string]interface{}:
+		return c.mapQuery(q, t, path)
+	case nil:
+		return func(v reflect.Value) bool {
+			return isNil(toInterface(v))
+		}, nil
+	default:
+		if !canEqual(q, t) {
+			return nil, &ErrTypeMismatch{Path: path, Op: "eq", Operand: q, Type: t}
+		}
+		return compileEquality(q, t), nil
+	}
+}
+
+func (c compiler) mapQuery(query Q, t reflect.Type, path string) (matcher, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	accessors := make([]accessor, len(keys))
+	matchers := make([]matcher, len(keys))
+
+	for i, key := range keys {
+		access, fieldType, err := c.path(t, key, path)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := c.query(query[key], fieldType, joinPath(path, key))
+		if err != nil {
+			return nil, err
+		}
+
+		accessors[i], matchers[i] = access, m
+	}
+
+	return func(v reflect.Value) bool {
+		for i, m := range matchers {
+			if !m(accessors[i](v)) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// pathStep is a step of a compiled path. literal, when valid, is the key of the map resolved
+// by the step holding the rest of the path (see resolvePath), which ends the path when found
+// with a value of type elem.
+type pathStep struct {
+	access  accessor
+	literal reflect.Value
+	elem    reflect.Type
+}
+
+// path resolves a Q key against t, returning the accessor and static type of the field.
+// Struct fields, map keys and indexes are resolved up front; from the first interface
+// on the rest of the path is resolved for every value.
+func (c compiler) path(t reflect.Type, key string, path string) (accessor, reflect.Type, error) {
+	if key == "" {
+		return func(v reflect.Value) reflect.Value { return v }, t, nil
+	}
+
+	var steps []pathStep
+	segments := splitPath(key)
+	literal := !strings.Contains(key, `\`)
+
+	// a path that doesn't resolve against t can still be a literal key of a map on the way
+	unknown := func(t reflect.Type) (accessor, reflect.Type, error) {
+		for i := len(steps) - 1; i >= 0; i-- {
+			if lk := steps[i].literal; lk.IsValid() {
+				lookup := pathStep{access: func(v reflect.Value) reflect.Value {
+					return v.MapIndex(lk)
+				}}
+				return pathAccessor(append(steps[:i:i], lookup), steps[i].elem)
+			}
+		}
+		return nil, nil, &ErrUnknownField{Path: joinPath(path, key), Type: t}
+	}
+
+walk:
+	for i, segment := range segments {
+		for t.Kind() == reflect.Ptr {
+			steps = append(steps, pathStep{access: func(v reflect.Value) reflect.Value {
+				if v.IsNil() {
+					return reflect.Value{}
+				}
+				return v.Elem()
+			}})
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Interface:
+			rest := segments[i:]
+			steps = append(steps, pathStep{access: func(v reflect.Value) reflect.Value {
+				return resolveSegments(v, rest, literal, c.tagNames)
+			}})
+			break walk
+
+		case reflect.Struct:
+			index, ok := fieldIndex(t, segment, c.tagNames)
+			if !ok {
+				return unknown(t)
+			}
+			steps = append(steps, pathStep{access: func(v reflect.Value) reflect.Value {
+				field, err := v.FieldByIndexErr(index)
+				if err != nil {
+					return reflect.Value{}
+				}
+				return field
+			}})
+			t = t.FieldByIndex(index).Type
+
+		case reflect.Map:
+			mk, ok := mapKey(t.Key(), segment)
+			if !ok {
+				return unknown(t)
+			}
+			step := pathStep{access: func(v reflect.Value) reflect.Value {
+				return v.MapIndex(mk)
+			}, elem: t.Elem()}
+			if literal && i < len(segments)-1 {
+				if lk, ok := mapKey(t.Key(), strings.Join(segments[i:], ".")); ok {
+					step.literal = lk
+				}
+			}
+			steps = append(steps, step)
+			t = t.Elem()
+
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 {
+				return unknown(t)
+			}
+			steps = append(steps, pathStep{access: func(v reflect.Value) reflect.Value {
+				if index >= v.Len() {
+					return reflect.Value{}
+				}
+				return v.Index(index)
+			}})
+			t = t.Elem()
+
+		default:
+			return unknown(t)
+		}
+	}
+
+	return pathAccessor(steps, t)
+}
+
+// pathAccessor returns the accessor running steps, and the static type t of the field
+func pathAccessor(steps []pathStep, t reflect.Type) (accessor, reflect.Type, error) {
+	if t.Kind() == reflect.Interface {
+		t = anyType
+	}
+
+	// the matchers of the field type can't evaluate the value of a literal key of another type
+	for i := range steps {
+		if steps[i].literal.IsValid() && steps[i].elem != t && t != anyType {
+			steps[i].literal = reflect.Value{}
+		}
+	}
+
+	return func(v reflect.Value) reflect.Value {
+		for _, step := range steps {
+			if !v.IsValid() {
+				break
+			}
+			if step.literal.IsValid() {
+				if found := v.MapIndex(step.literal); found.IsValid() {
+					return found
+				}
+			}
+			v = step.access(v)
+		}
+		return v
+	}, t, nil
+}
+
+func (c compiler) predicate(p P, t reflect.Type, path string) (matcher, error) {
+	op := describe(p)
+	if op == nil {
+		return func(v reflect.Value) bool {
+			return p(toInterface(v))
+		}, nil
+	}
+
+	if err := checkOperator(op, t, path); err != nil {
+		return nil, err
+	}
+
+	switch op.name {
+	case "and", "or", "not":
+		children := make([]matcher, len(op.children))
+		for i, child := range op.children {
+			m, err := c.query(child, t, path)
+			if err != nil {
+				return nil, err
+			}
+			children[i] = m
+		}
+
+		switch op.name {
+		case "and":
+			return func(v reflect.Value) bool {
+				for _, m := range children {
+					if !m(v) {
+						return false
+					}
+				}
+				return true
+			}, nil
+		case "or":
+			return func(v reflect.Value) bool {
+				for _, m := range children {
+					if m(v) {
+						return true
+					}
+				}
+				return false
+			}, nil
+		default:
+			return func(v reflect.Value) bool {
+				return !children[0](v)
+			}, nil
+		}
+
+	case "eq":
+		return compileEquality(op.args[0], t), nil
+
+	case "strict":
+		return c.query(strictQuery(op.children[0], ""), t, path)
+
+	case "gt", "gte", "lt", "lte":
+		if op.strict {
+			break
+		}
+		if m := compileComparison(op.name, op.args[0], t); m != nil {
+			return m, nil
+		}
+	}
+
+	return func(v reflect.Value) bool {
+		return p(toInterface(v))
+	}, nil
+}
+
+// compileEquality matches values equal to val (see isEqual), avoiding
+// interface conversions for numbers, strings and booleans of a known type
+func compileEquality(val interface{}, t reflect.Type) matcher {
+	generic := func(v reflect.Value) bool {
+		return isEqual(toInterface(v), val)
+	}
+
+	if num, ok := toNumber(val); ok {
+		if number := numberOf(t); number != nil {
+			return func(v reflect.Value) bool {
+				if !v.IsValid() {
+					return false
+				}
+				return number(v) == num
+			}
+		}
+		return generic
+	}
+
+	if reflect.TypeOf(val) != t {
+		return generic
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		s := reflect.ValueOf(val).String()
+		return func(v reflect.Value) bool {
+			return v.IsValid() && v.String() == s
+		}
+	case reflect.Bool:
+		b := reflect.ValueOf(val).Bool()
+		return func(v reflect.Value) bool {
+			return v.IsValid() && v.Bool() == b
+		}
+	default:
+		return generic
+	}
+}
+
+// compileComparison returns a matcher for a gt/gte/lt/lte operator (see compareValues),
+// or nil when there's no faster way to compare values of type t with threshold
+func compileComparison(name string, threshold interface{}, t reflect.Type) matcher {
+	var compare func(reflect.Value) int
+
+	if num, ok := toNumber(threshold); ok {
+		switch {
+		case isIntKind(t.Kind()) && isIntKind(reflect.ValueOf(threshold).Kind()):
+			n := reflect.ValueOf(threshold).Int()
+			compare = func(v reflect.Value) int {
+				return cmpOrdered(v.Int(), n)
+			}
+		default:
+			number := numberOf(t)
+			if number == nil {
+				return nil
+			}
+			compare = func(v reflect.Value) int {
+				return cmpOrdered(number(v), num)
+			}
+		}
+	} else if s, ok := threshold.(string); ok && t == stringType {
+		compare = func(v reflect.Value) int {
+			return cmpOrdered(v.String(), s)
+		}
+	} else {
+		return nil
+	}
+
+	accept := comparisonResult(name)
+	return func(v reflect.Value) bool {
+		if !v.IsValid() {
+			// nil sorts first (see compareValues)
+			return accept(-1)
+		}
+		return accept(compare(v))
+	}
+}
+
+var (
+	anyType    = reflect.TypeOf((*interface{})(nil)).Elem()
+	stringType = reflect.TypeOf("")
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// comparisonResult returns whether a compare result satisfies a gt/gte/lt/lte operator
+func comparisonResult(name string) func(int) bool {
+	switch name {
+	case "gt":
+		return func(c int) bool { return c > 0 }
+	case "gte":
+		return func(c int) bool { return c >= 0 }
+	case "lt":
+		return func(c int) bool { return c < 0 }
+	default:
+		return func(c int) bool { return c <= 0 }
+	}
+}
+
+// numberOf returns a float64 reader for values of a numeric type, nil for other types
+func numberOf(t reflect.Type) func(reflect.Value) float64 {
+	switch {
+	case isIntKind(t.Kind()):
+		return func(v reflect.Value) float64 { return float64(v.Int()) }
+	case isUintKind(t.Kind()):
+		return func(v reflect.Value) float64 { return float64(v.Uint()) }
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return func(v reflect.Value) float64 { return v.Float() }
+	default:
+		return nil
+	}
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+func cmpOrdered[V int64 | float64 | string](a, b V) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// toInterface returns the value held by v, nil for an invalid value
+func toInterface(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func joinPath(path, key string) string {
+	if path == "" || key == "" {
+		return path + key
+	}
+	return path + "." + key
+}
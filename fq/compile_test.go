@@ -0,0 +1,223 @@
+package fq
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileMatchesFilter(t *testing.T) {
+	products := getTestProducts()
+	baseTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	queries := map[string]Query{
+		"nil":               nil,
+		"empty":             Q{},
+		"int equality":      Q{"ID": 3},
+		"float equality":    Q{"ID": 3.0},
+		"string equality":   Q{"Name": "Laptop Pro"},
+		"bool equality":     Q{"InStock": false},
+		"time equality":     Q{"CreatedAt": baseTime},
+		"gt":                Q{"Price": Gt(1000)},
+		"lte float":         Q{"Rating": Lte(4.1)},
+		"gte int":           Q{"Stock": Gte(45)},
+		"string comparison": Q{"Name": Lt("M")},
+		"time comparison":   Q{"CreatedAt": Gt(baseTime.AddDate(0, 2, 0))},
+		"in":                Q{"ID": In(1, 3, 5)},
+		"contains":          Q{"Name": Contains("Tablet")},
+		"match regexp":      Q{"Name": Match(regexp.MustCompile("^(Laptop|Designer)"))},
+		"array":             Q{"Tags": HasItem("premium")},
+		"nested struct":     Q{"Manufacturer": Q{"Country": "USA"}},
+		"path":              Q{"Address.City": Contains("Angeles")},
+		"map path":          Q{"Properties.warranty": Gt(1)},
+		"slice index":       Q{"Tags.0": "premium"},
+		"nil field":         Q{"Properties.material": nil},
+		"logical": And(
+			Q{"InStock": true},
+			Or(
+				Q{"Price": Lt(200)},
+				And(Q{"Price": Gt(800)}, Q{"Rating": Gt(4.5)}),
+			),
+			Not(Q{"ID": 4}),
+		),
+		"primitive operands": Q{"ID": And(Or(3, 4), Not(5), Or(Eq(3), Eq(4)))},
+		"custom predicate": Q{"": func(v interface{}) bool {
+			return v.(Product).Stock > 50
+		}},
+	}
+
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			expected, err := Filter(products, query, 0, 0)
+			if err != nil {
+				t.Fatalf("Unexpected Filter error: %v", err)
+			}
+
+			compiled, err := Compile[Product](query)
+			if err != nil {
+				t.Fatalf("Unexpected Compile error: %v", err)
+			}
+
+			result, err := compiled.Filter(products, 0, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(result) != len(expected) {
+				t.Fatalf("Expected %d products, got %d", len(expected), len(result))
+			}
+			for i := range result {
+				if result[i].ID != expected[i].ID {
+					t.Errorf("Expected product %d at %d, got %d", expected[i].ID, i, result[i].ID)
+				}
+			}
+
+			matched := map[int]bool{}
+			for _, product := range expected {
+				matched[product.ID] = true
+			}
+			for _, product := range products {
+				if compiled.Match(product) != matched[product.ID] {
+					t.Errorf("Match disagrees with Filter on product %d", product.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileDynamicItems(t *testing.T) {
+	items := []interface{}{
+		map[string]interface{}{"id": float64(1), "user": map[string]interface{}{"city": "Paris"}},
+		map[string]interface{}{"id": float64(2), "user": map[string]interface{}{"city": "Lyon"}},
+		&Address{City: "Paris"},
+	}
+
+	compiled, err := Compile[interface{}](Or(Q{"user.city": "Paris"}, Q{"City": "Paris"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result, err := compiled.Filter(items, 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("Expected 2 items in Paris, got %v", result)
+	}
+
+	input := make(chan interface{}, len(items))
+	for _, item := range items {
+		input <- item
+	}
+	close(input)
+
+	dataCh, errCh := compiled.FilterC(input, 1, 0)
+	streamed, errors := collectResults(dataCh, errCh)
+	if len(errors) > 0 {
+		t.Errorf("Unexpected errors: %v", errors)
+	}
+	if len(streamed) != 1 || streamed[0] != items[2] {
+		t.Errorf("Expected the address after skipping 1, got %v", streamed)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          Query
+		errorSubstring string
+	}{
+		{"unknown field", Q{"Nme": "x"}, `unknown field "Nme"`},
+		{"unknown nested field", Q{"Address": Q{"Town": "x"}}, `unknown field "Address.Town"`},
+		{"unknown path", Q{"Address.Town": "x"}, `unknown field "Address.Town"`},
+		{"field of scalar", Q{"Price.Amount": 1}, `unknown field "Price.Amount"`},
+		{"bad index", Q{"Tags.first": "x"}, `unknown field "Tags.first"`},
+		{"incomparable operand", Q{"Tags": Gt([]string{"a"})}, "not a number, string or time"},
+		{"operand inside logic", Q{"Price": Or(Lt(10), Gt(nil))}, "not a number, string or time"},
+		{"bad pattern", Q{"Name": Match(42)}, "not a string or *regexp.Regexp"},
+		{"bad radius", Q{"Tags": GeoWithin(0, 0, -1)}, "negative radius"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Compile[Product](tc.query)
+			if err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+			if !strings.Contains(err.Error(), tc.errorSubstring) {
+				t.Errorf("Expected error containing %q, got: %v", tc.errorSubstring, err)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	gt := Gt(5)
+	if op := describe(gt); op == nil || op.name != "gt" || op.args[0] != 5 {
+		t.Errorf("Expected gt operator with operand 5, got %+v", op)
+	}
+
+	not := Not(Q{"a": 1})
+	if op := describe(not); op == nil || op.name != "not" || len(op.children) != 1 {
+		t.Errorf("Expected not operator with 1 child, got %+v", op)
+	}
+
+	called := false
+	custom := func(v interface{}) bool {
+		called = true
+		return true
+	}
+	if op := describe(custom); op != nil || called {
+		t.Errorf("Expected custom predicates to stay opaque and uncalled, got %+v", op)
+	}
+
+	if !gt(6) || gt(4) {
+		t.Error("Expected described operators to keep evaluating normally")
+	}
+}
+
+// Performance Benchmarks -------------------------------------------------
+
+func getManyTestProducts(n int) []Product {
+	products := getTestProducts()
+	many := make([]Product, 0, n)
+	for i := 0; i < n; i++ {
+		product := products[i%len(products)]
+		product.ID = i
+		many = append(many, product)
+	}
+	return many
+}
+
+var benchmarkQuery = And(
+	Q{"InStock": true},
+	Q{"Price": Lt(1000), "Manufacturer.Country": In("USA", "China")},
+	Or(Q{"Rating": Gte(4.5)}, Q{"Stock": Gt(100)}),
+)
+
+func BenchmarkFilterLarge(b *testing.B) {
+	products := getManyTestProducts(10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Filter(products, benchmarkQuery, 0, 0); err != nil {
+			b.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func BenchmarkCompiledFilterLarge(b *testing.B) {
+	products := getManyTestProducts(10000)
+	compiled, err := Compile[Product](benchmarkQuery)
+	if err != nil {
+		b.Fatalf("Unexpected error: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := compiled.Filter(products, 0, 0); err != nil {
+			b.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
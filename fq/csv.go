@@ -0,0 +1,198 @@
+package fq
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVOption configures a CSV source
+type CSVOption func(*csvOptions)
+
+type csvOptions struct {
+	delimiter  rune
+	comment    rune
+	inferTypes bool
+}
+
+// WithDelimiter sets the field delimiter, ',' by default ('\t' for TSV).
+// Tab-delimited fields aren't quoted: their quotes are read as is.
+func WithDelimiter(delimiter rune) CSVOption {
+	return func(o *csvOptions) {
+		o.delimiter = delimiter
+	}
+}
+
+// WithComment skips the lines starting with comment, there are no comments by default
+func WithComment(comment rune) CSVOption {
+	return func(o *csvOptions) {
+		o.comment = comment
+	}
+}
+
+// WithTypeInference converts the fields that look like JSON numbers to float64, true and
+// false to bool and RFC 3339 timestamps to time.Time, so that they compare like the values
+// they stand for. Other fields, and all fields by default, are strings.
+func WithTypeInference() CSVOption {
+	return func(o *csvOptions) {
+		o.inferTypes = true
+	}
+}
+
+// CSVSourceStream creates a channel of the rows read from the CSV r and a channel for errors.
+// The first row is the header: rows are map[string]interface{} keyed by its column names.
+// Rows that can't be parsed, or with another number of fields than the header, are reported
+// as errors with their line number. gzip and bzip2 data is decompressed. r isn't closed.
+func CSVSourceStream(r io.Reader, opts ...CSVOption) (<-chan interface{}, <-chan error) {
+	output := make(chan interface{}, 100)
+	errCh := make(chan error, 10)
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		for row, err := range CSVSourceSeq(r, opts...) {
+			if err != nil {
+				errCh <- err
+				continue
+			}
+			output <- row
+		}
+	}()
+
+	return output, errCh
+}
+
+// CSVSourceSeq iterates over the rows read from the CSV r like CSVSourceStream, yielding
+// errors along with a nil row
+func CSVSourceSeq(r io.Reader, opts ...CSVOption) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		if err := readCSV(r, "", newCSVOptions(opts), yield); err != nil {
+			yield(nil, fmt.Errorf("error reading input: %w", err))
+		}
+	}
+}
+
+// CSVFileSourceSeq iterates over the rows of a CSV file like CSVSourceSeq. The file is opened
+// when iteration starts and closed when it stops.
+func CSVFileSourceSeq(filePath string, opts ...CSVOption) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to open file: %w", err))
+			return
+		}
+		defer file.Close()
+
+		if err := readCSV(file, filePath, newCSVOptions(opts), yield); err != nil {
+			yield(nil, fmt.Errorf("error reading file: %w", err))
+		}
+	}
+}
+
+func newCSVOptions(opts []CSVOption) *csvOptions {
+	o := &csvOptions{delimiter: ','}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// readCSV yields the rows of r, the content of the file name if any, until yield returns
+// false, returning the read error if any
+func readCSV(r io.Reader, name string, o *csvOptions, yield func(interface{}, error) bool) error {
+	decompressed, err := decompress(r, name)
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(decompressed)
+	reader.Comma = o.delimiter
+	reader.Comment = o.comment
+	reader.ReuseRecord = true
+	// TSV has no quoting, a field like 55" screen isn't an error
+	reader.LazyQuotes = o.delimiter == '\t'
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("header: %w", err)
+	}
+
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, column := range header {
+		if i == 0 {
+			// Excel writes a byte order mark
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		if seen[column] {
+			return fmt.Errorf("header: duplicate column %q", column)
+		}
+		seen[column] = true
+		columns[i] = column
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// the reader moves on to the next row
+			if !yield(nil, fmt.Errorf("line %d: error parsing CSV: %w", parseErr.StartLine, parseErr.Err)) {
+				return nil
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if o.inferTypes {
+				row[column] = inferType(record[i])
+			} else {
+				row[column] = record[i]
+			}
+		}
+
+		if !yield(row, nil) {
+			return nil
+		}
+	}
+}
+
+// jsonNumber matches the numbers of the JSON grammar, excluding ones like 007 or 1e3f
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// inferType converts a CSV field to the float64, bool or time.Time it stands for if any
+func inferType(field string) interface{} {
+	switch {
+	case jsonNumber.MatchString(field):
+		if f, err := strconv.ParseFloat(field, 64); err == nil {
+			return f
+		}
+	case strings.EqualFold(field, "true"):
+		return true
+	case strings.EqualFold(field, "false"):
+		return false
+	case len(field) >= len("2006-01-02T15:04:05Z") && field[4] == '-':
+		if t, err := time.Parse(time.RFC3339Nano, field); err == nil {
+			return t
+		}
+	}
+	return field
+}
@@ -0,0 +1,115 @@
+package fq
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVSource(t *testing.T) {
+	content := "\ufeffid,name,price,in_stock,created_at,zip\n" +
+		"1,Laptop,999.99,true,2024-01-15T10:00:00Z,02134\n" +
+		"2,\"Desk, oak\",300,FALSE,2024-03-01T08:30:00+01:00,10001\n" +
+		"3,Lamp,1e2\n" +
+		"4,\"Chair \"\"Pro\"\"\",45,false,yesterday,-\n"
+
+	results, errs := collectResults(CSVSourceStream(strings.NewReader(content), WithTypeInference()))
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "line 4: error parsing CSV: wrong number of fields") {
+		t.Errorf("Expected a field count error on line 4, got %v", errs)
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "Laptop", "price": 999.99, "in_stock": true,
+			"created_at": time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC), "zip": "02134"},
+		map[string]interface{}{"id": 2.0, "name": "Desk, oak", "price": 300.0, "in_stock": false,
+			"created_at": time.Date(2024, 3, 1, 7, 30, 0, 0, time.UTC), "zip": 10001.0},
+		map[string]interface{}{"id": 4.0, "name": `Chair "Pro"`, "price": 45.0, "in_stock": false,
+			"created_at": "yesterday", "zip": "-"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d rows, got %v", len(expected), results)
+	}
+	for i, row := range results {
+		for key, want := range expected[i].(map[string]interface{}) {
+			got := row.(map[string]interface{})[key]
+			if wantTime, ok := want.(time.Time); ok {
+				if gotTime, ok := got.(time.Time); !ok || !gotTime.Equal(wantTime) {
+					t.Errorf("Row %d: expected %s %v, got %v", i, key, want, got)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Row %d: expected %s %v (%T), got %v (%T)", i, key, want, want, got, got)
+			}
+		}
+	}
+
+	matches, err := Filter(results, Q{"price": Lt(500), "created_at": Gt(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))}, 0, 0)
+	if err != nil || len(matches) != 1 || matches[0].(map[string]interface{})["id"] != 2.0 {
+		t.Errorf("Expected inferred values to compare, got %v (%v)", matches, err)
+	}
+
+	t.Run("strings by default", func(t *testing.T) {
+		results, _ := collectResults(CSVSourceStream(strings.NewReader("a,b\n1,true\n")))
+		if !reflect.DeepEqual(results, []interface{}{map[string]interface{}{"a": "1", "b": "true"}}) {
+			t.Errorf("Expected string fields, got %v", results)
+		}
+	})
+
+	t.Run("tsv with comments", func(t *testing.T) {
+		tsv := "# export\nid\tnote\n# skipped\n1\ta, b\n"
+		var rows []interface{}
+		for row, err := range CSVSourceSeq(strings.NewReader(tsv), WithDelimiter('\t'), WithComment('#'), WithTypeInference()) {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			rows = append(rows, row)
+		}
+		if !reflect.DeepEqual(rows, []interface{}{map[string]interface{}{"id": 1.0, "note": "a, b"}}) {
+			t.Errorf("Unexpected rows %v", rows)
+		}
+	})
+
+	t.Run("tsv quotes", func(t *testing.T) {
+		tsv := "name\tsize\nTV 55\" screen\t55\n"
+		var rows []interface{}
+		for row, err := range CSVSourceSeq(strings.NewReader(tsv), WithDelimiter('\t'), WithTypeInference()) {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			rows = append(rows, row)
+		}
+		if !reflect.DeepEqual(rows, []interface{}{map[string]interface{}{"name": `TV 55" screen`, "size": 55.0}}) {
+			t.Errorf("Unexpected rows %v", rows)
+		}
+
+		// csv quoting is still checked
+		for _, err := range CSVSourceSeq(strings.NewReader("name,size\nTV 55\" screen,55\n")) {
+			if err == nil || !strings.Contains(err.Error(), `bare "`) {
+				t.Errorf("Expected a bare quote error, got %v", err)
+			}
+		}
+	})
+
+	t.Run("files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.csv")
+		if err := os.WriteFile(path, []byte("a,a\n1,2\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		for _, err := range CSVFileSourceSeq(path) {
+			if err == nil || err.Error() != `error reading file: header: duplicate column "a"` {
+				t.Errorf("Expected a duplicate column error, got %v", err)
+			}
+		}
+
+		for _, err := range CSVFileSourceSeq(filepath.Join(t.TempDir(), "missing.csv")) {
+			if err == nil || !strings.Contains(err.Error(), "failed to open file") {
+				t.Errorf("Expected an open error, got %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package fq
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ErrUnknownField reports a query field that doesn't exist on the queried type
+type ErrUnknownField struct {
+	Path string       // field path in the query
+	Type reflect.Type // type the last path segment was looked up in
+}
+
+func (e *ErrUnknownField) Error() string {
+	return fmt.Sprintf("unknown field %q in %s", e.Path, e.Type)
+}
+
+// ErrTypeMismatch reports an operand that can never match values of its field type
+type ErrTypeMismatch struct {
+	Path    string       // field path in the query, empty for the queried item itself
+	Op      string       // operator name, "eq" for plain values
+	Operand interface{}  // offending operand
+	Type    reflect.Type // type of the field values
+}
+
+func (e *ErrTypeMismatch) Error() string {
+	return fmt.Sprintf("%s: %s: operand %v (%T) can't match values of type %s",
+		describePath(e.Path), e.Op, e.Operand, e.Operand, e.Type)
+}
+
+// ErrInvalidOperand reports an operator argument that is invalid for the operator itself
+type ErrInvalidOperand struct {
+	Path    string      // field path in the query, empty for the queried item itself
+	Op      string      // operator name
+	Operand interface{} // offending operand
+	Reason  string
+}
+
+func (e *ErrInvalidOperand) Error() string {
+	return fmt.Sprintf("%s: %s: invalid operand %v: %s", describePath(e.Path), e.Op, e.Operand, e.Reason)
+}
+
+// ErrUnknownOperator reports an operator of a query document that doesn't exist (see ParseJSONQuery)
+type ErrUnknownOperator struct {
+	Path string // field path in the query, empty for the queried item itself
+	Op   string // operator as written in the document, like "$lt"
+}
+
+func (e *ErrUnknownOperator) Error() string {
+	return fmt.Sprintf("%s: unknown operator %s", describePath(e.Path), e.Op)
+}
+
+// describePath names a query path in error messages
+func describePath(path string) string {
+	if path == "" {
+		return "query"
+	}
+	return fmt.Sprintf("field %q", path)
+}
@@ -0,0 +1,178 @@
+package fq
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Explanation is the evaluation trace of a query against an item (see Explain)
+type Explanation struct {
+	Key      string         // Q key the query was evaluated for, empty for the item itself
+	Path     string         // field path of Value from the item
+	Value    interface{}    // value the query was evaluated against
+	Op       string         // operator name, "q" for map queries, "eq" for plain values, "nil" for nil and "custom" for other predicates
+	Operand  interface{}    // operator argument, a []interface{} for operators with several
+	Result   bool           // outcome of the query
+	Err      error          // evaluation error, like a strict comparison mismatch or a panic
+	Children []*Explanation // traces of the queries combined by Op
+}
+
+// Explain evaluates query against item like Filter does, recording the outcome of every
+// condition. Unlike Filter it doesn't stop at the first failing condition of And, Or or Q,
+// so the trace shows every condition. A nil query matches every item, like Filter, and is
+// explained as an empty Q. Only WithTagNames applies to Explain.
+func Explain(query Query, item interface{}, opts ...Option) *Explanation {
+	if query == nil {
+		return &Explanation{Value: recordValue(item), Op: "q", Result: true}
+	}
+	return explain(query, recordValue(item), "", newOptions(opts).tagNames)
+}
+
+func explain(query Query, value interface{}, path string, tagNames bool) (e *Explanation) {
+	e = &Explanation{Path: path, Value: value}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e.Result = false
+			e.Err = recoveredError(r, "panic during evaluation")
+		}
+	}()
+
+	switch q := query.(type) {
+	case Q:
+		explainMapQuery(e, q, tagNames)
+	case map[string]interface{}:
+		explainMapQuery(e, q, tagNames)
+	case P:
+		explainPredicate(e, q, tagNames)
+	case func(interface{}) bool:
+		explainPredicate(e, q, tagNames)
+	case nil:
+		e.Op = "nil"
+		e.Result = isNil(value)
+	default:
+		e.Op, e.Operand = "eq", q
+		e.Result = isEqual(value, q)
+	}
+
+	return e
+}
+
+func explainMapQuery(e *Explanation, query Q, tagNames bool) {
+	e.Op = "q"
+	e.Result = true
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := e.Value
+		if key != "" {
+			value = fieldOf(e.Value, key, tagNames)
+		}
+
+		child := explain(query[key], value, joinPath(e.Path, key), tagNames)
+		child.Key = key
+
+		e.Children = append(e.Children, child)
+		e.Result = e.Result && child.Result
+	}
+}
+
+func explainPredicate(e *Explanation, p P, tagNames bool) {
+	op := describe(p)
+	if op == nil {
+		e.Op = "custom"
+		e.Result = p(e.Value)
+		return
+	}
+
+	e.Op = op.name
+	switch len(op.args) {
+	case 0:
+	case 1:
+		e.Operand = op.args[0]
+	default:
+		e.Operand = op.args
+	}
+
+	children := op.children
+	if op.name == "strict" {
+		children = []Query{strictQuery(op.children[0], "")}
+	}
+
+	if len(children) == 0 {
+		e.Result = p(e.Value)
+		return
+	}
+
+	for _, child := range children {
+		e.Children = append(e.Children, explain(child, e.Value, e.Path, tagNames))
+	}
+
+	switch op.name {
+	case "or":
+		for _, child := range e.Children {
+			e.Result = e.Result || child.Result
+		}
+	case "not":
+		e.Result = !e.Children[0].Result && e.Children[0].Err == nil
+	default:
+		e.Result = true
+		for _, child := range e.Children {
+			e.Result = e.Result && child.Result
+		}
+	}
+}
+
+// String renders the explanation as an indented tree, one condition per line
+func (e *Explanation) String() string {
+	var b strings.Builder
+	e.write(&b, 0)
+	return b.String()
+}
+
+func (e *Explanation) write(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	if e.Result {
+		b.WriteString("✓ ")
+	} else {
+		b.WriteString("✗ ")
+	}
+
+	if e.Key != "" {
+		b.WriteString(e.Key + ": ")
+	}
+	b.WriteString(e.Op)
+
+	if len(e.Children) == 0 && e.Op != "q" {
+		if e.Op != "nil" && e.Op != "custom" {
+			b.WriteString(" " + formatValue(e.Operand))
+		}
+		b.WriteString(" (value: " + formatValue(e.Value) + ")")
+	}
+	if e.Err != nil {
+		b.WriteString(" error: " + e.Err.Error())
+	}
+	b.WriteString("\n")
+
+	for _, child := range e.Children {
+		child.write(b, depth+1)
+	}
+}
+
+// formatValue formats values for explanations, quoting strings
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return fmt.Sprintf("%q", val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
@@ -0,0 +1,101 @@
+package fq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	product := getTestProducts()[1] // Budget Tablet, 299.99, China
+
+	query := And(
+		Q{"InStock": true, "Price": Lt(200)},
+		Or(
+			Q{"Manufacturer.Country": "USA"},
+			Not(Q{"Tags": HasItem("budget")}),
+		),
+	)
+
+	e := Explain(query, product)
+	if e.Result != eval(query, product) || e.Result {
+		t.Fatalf("Expected a non-matching explanation, got %v", e.Result)
+	}
+	if e.Op != "and" || len(e.Children) != 2 {
+		t.Fatalf("Expected and with 2 children, got %s with %d", e.Op, len(e.Children))
+	}
+
+	fields := e.Children[0]
+	if fields.Op != "q" || fields.Result || len(fields.Children) != 2 {
+		t.Fatalf("Expected failing q with 2 fields, got %+v", fields)
+	}
+
+	inStock, price := fields.Children[0], fields.Children[1]
+	if inStock.Key != "InStock" || inStock.Op != "eq" || inStock.Value != true || !inStock.Result {
+		t.Errorf("Unexpected InStock explanation: %+v", inStock)
+	}
+	if price.Key != "Price" || price.Op != "lt" || price.Operand != 200 || price.Value != 299.99 || price.Result {
+		t.Errorf("Unexpected Price explanation: %+v", price)
+	}
+
+	not := e.Children[1].Children[1]
+	if not.Op != "not" || not.Result || !not.Children[0].Result {
+		t.Errorf("Unexpected not explanation: %+v", not)
+	}
+
+	country := e.Children[1].Children[0].Children[0]
+	if country.Path != "Manufacturer.Country" || country.Value != "China" {
+		t.Errorf("Unexpected nested path explanation: %+v", country)
+	}
+
+	tree := e.String()
+	for _, want := range []string{
+		"✗ and\n",
+		"    ✓ InStock: eq true (value: true)\n",
+		"    ✗ Price: lt 200 (value: 299.99)\n",
+		`      ✗ Manufacturer.Country: eq "USA" (value: "China")`,
+		`        ✓ Tags: hasitem "budget" (value: [budget entertainment])`,
+	} {
+		if !strings.Contains(tree, want) {
+			t.Errorf("Expected tree to contain %q, got:\n%s", want, tree)
+		}
+	}
+}
+
+func TestExplainNilQuery(t *testing.T) {
+	products := getTestProducts()
+	if matches, err := Filter(products, nil, 0, 0); err != nil || len(matches) != len(products) {
+		t.Fatalf("Expected a nil query to match every product, got %d (%v)", len(matches), err)
+	}
+
+	e := Explain(nil, products[1])
+	if !e.Result || e.Op != "q" || len(e.Children) != 0 {
+		t.Errorf("Expected a nil query to match like Filter, got %+v", e)
+	}
+
+	// a nil condition still matches nil values only
+	if e := Explain(Q{"Name": nil}, products[1]); e.Result {
+		t.Errorf("Expected a nil condition not to match a name, got %+v", e)
+	}
+}
+
+func TestExplainErrors(t *testing.T) {
+	item := map[string]interface{}{"price": "cheap"}
+
+	e := Explain(Strict(Q{"price": Lt(5)}), item)
+	var mismatch *ErrTypeMismatch
+	if e.Result || e.Op != "strict" {
+		t.Fatalf("Expected failing strict explanation, got %+v", e)
+	}
+	leaf := e.Children[0].Children[0]
+	if !errors.As(leaf.Err, &mismatch) || leaf.Result {
+		t.Errorf("Expected mismatch on the comparison, got %+v", leaf)
+	}
+
+	e = Explain(Q{"": func(v interface{}) bool {
+		panic("boom")
+	}}, item)
+	if e.Result || e.Children[0].Op != "custom" || e.Children[0].Err == nil {
+		t.Errorf("Expected recovered panic in custom predicate, got %+v", e.Children[0])
+	}
+}
@@ -0,0 +1,156 @@
+package fq
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structFieldsCache holds the resolved field names of each struct type (fieldsKey -> map[string][]int)
+var structFieldsCache sync.Map
+
+// fieldsKey identifies the field names of a struct type, with or without tag names
+type fieldsKey struct {
+	t        reflect.Type
+	tagNames bool
+}
+
+// structField looks up a struct field by its Go name. With tagNames (see WithTagNames) the
+// name given in an `fq:"..."` tag and then the one in a `json:"..."` tag are tried next.
+// Promoted fields of embedded structs resolve the way they do in Go and encoding/json.
+func structField(v reflect.Value, name string, tagNames bool) reflect.Value {
+	index, ok := fieldIndex(v.Type(), name, tagNames)
+	if !ok {
+		return reflect.Value{}
+	}
+
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		// nil embedded pointer
+		return reflect.Value{}
+	}
+	return field
+}
+
+// fieldIndex returns the index sequence of the named field of struct type t
+func fieldIndex(t reflect.Type, name string, tagNames bool) ([]int, bool) {
+	key := fieldsKey{t, tagNames}
+	fields, ok := structFieldsCache.Load(key)
+	if !ok {
+		fields, _ = structFieldsCache.LoadOrStore(key, resolveStructFields(t, tagNames))
+	}
+
+	index, ok := fields.(map[string][]int)[name]
+	return index, ok
+}
+
+// resolveStructFields builds the name -> index lookup table of a struct type from its
+// Go names, layered over fq tag names over json tag names with tagNames
+func resolveStructFields(t reflect.Type, tagNames bool) map[string][]int {
+	if !tagNames {
+		return collectFields(t, "")
+	}
+	names := collectFields(t, "json")
+	for name, index := range collectFields(t, "fq") {
+		names[name] = index
+	}
+	for name, index := range collectFields(t, "") {
+		names[name] = index
+	}
+	return names
+}
+
+// collectFields walks t breadth first and names every accessible field.
+// With an empty tagKey fields are named like Go does (embedded structs are both a field and promote
+// their fields), otherwise like encoding/json does for that tag key (tagged names, untagged embedded
+// structs only promote). Shallower fields hide deeper ones; same-depth conflicts are resolved in
+// favor of a single tagged field, or dropped as ambiguous.
+func collectFields(t reflect.Type, tagKey string) map[string][]int {
+	type level struct {
+		typ   reflect.Type
+		index []int
+	}
+	type candidate struct {
+		index  []int
+		tagged bool
+	}
+
+	names := map[string][]int{}
+	hidden := map[string]bool{}
+	visited := map[reflect.Type]bool{}
+	current := []level{{typ: t}}
+
+	for len(current) > 0 {
+		var next []level
+		found := map[string][]candidate{}
+
+		for _, l := range current {
+			if visited[l.typ] {
+				continue
+			}
+			visited[l.typ] = true
+
+			for i := 0; i < l.typ.NumField(); i++ {
+				sf := l.typ.Field(i)
+				index := append(append([]int(nil), l.index...), i)
+
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				embedsStruct := sf.Anonymous && ft.Kind() == reflect.Struct
+
+				if !sf.IsExported() && !embedsStruct {
+					continue
+				}
+
+				name, tagged := sf.Name, false
+				if tagKey != "" {
+					tag := sf.Tag.Get(tagKey)
+					if tag == "-" {
+						continue
+					}
+					if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+						name, tagged = tagName, true
+					}
+				}
+
+				if embedsStruct && !tagged {
+					next = append(next, level{typ: ft, index: index})
+					if tagKey != "" || !sf.IsExported() {
+						continue
+					}
+				}
+
+				found[name] = append(found[name], candidate{index: index, tagged: tagged})
+			}
+		}
+
+		for name, candidates := range found {
+			if _, ok := names[name]; ok || hidden[name] {
+				continue
+			}
+
+			var dominant []candidate
+			if len(candidates) == 1 {
+				dominant = candidates
+			} else {
+				for _, c := range candidates {
+					if c.tagged {
+						dominant = append(dominant, c)
+					}
+				}
+			}
+
+			if len(dominant) == 1 {
+				names[name] = dominant[0].index
+			} else {
+				hidden[name] = true
+			}
+		}
+
+		current = next
+	}
+
+	return names
+}
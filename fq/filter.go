@@ -1,6 +1,7 @@
 package fq
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 )
@@ -14,32 +15,106 @@ type Q map[string]interface{}
 // P is a function that evaluates whether a value meets a condition
 type P func(interface{}) bool
 
-// Filter filters data based on any query type
-func Filter[T any](data []T, query Query, skip int, limit int) (result []T, err error) {
+// Filter filters data based on any query type.
+// The query is compiled for T once per call (see Compile), queries that fail Validate
+// for T are rejected with the validation error.
+func Filter[T any](data []T, query Query, skip int, limit int, opts ...Option) ([]T, error) {
+	return FilterCtx(context.Background(), data, query, skip, limit, opts...)
+}
+
+// FilterCtx filters data like Filter but stops as soon as ctx is done.
+// On cancellation it returns the items matched so far along with ctx.Err().
+func FilterCtx[T any](ctx context.Context, data []T, query Query, skip int, limit int, opts ...Option) ([]T, error) {
+	o := newOptions(opts)
+	obs := newObserver[T](o)
+	if err := ctx.Err(); err != nil {
+		obs.complete(err)
+		return nil, err
+	}
+
+	if query == nil {
+		result := data
+		if skip != 0 || (limit != 0 && limit < len(data)) {
+			result = data[min(skip, len(data)):min(skip+limit, len(data))]
+		}
+		obs.stats.Returned = len(result)
+		obs.complete(nil)
+		return result, nil
+	}
+
+	m, err := compileItemQuery(query, typeOf[T](), o.tagNames)
+	if err != nil {
+		obs.complete(err)
+		return nil, err
+	}
+
+	values := reflect.ValueOf(data)
+	return filterSlice(ctx, data, func(i int) bool {
+		return m(values.Index(i))
+	}, skip, limit, obs)
+}
+
+// FilterC filters data based on any query type (like Filter but with channel io)
+func FilterC[T any](input <-chan T, query Query, skip int, limit int, opts ...Option) (<-chan T, <-chan error) {
+	return FilterCCtx(context.Background(), input, query, skip, limit, opts...)
+}
+
+// FilterCCtx filters data like FilterC but stops as soon as ctx is done.
+// On cancellation ctx.Err() is reported on the error channel and both channels are closed,
+// even if the consumer is no longer reading from them.
+func FilterCCtx[T any](ctx context.Context, input <-chan T, query Query, skip int, limit int, opts ...Option) (<-chan T, <-chan error) {
+	o := newOptions(opts)
+	obs := newObserver[T](o)
+	match, err := compileMatch[T](query, o)
+	if err != nil {
+		obs.complete(err)
+		return failedC[T](err)
+	}
+
+	return filterChan(ctx, input, match, skip, limit, obs)
+}
+
+// filterSlice collects the items of data for which match (called with the item index) is true
+// obs completes once it returns.
+func filterSlice[T any](ctx context.Context, data []T, match func(int) bool, skip int, limit int, obs *observer[T]) (result []T, err error) {
+	current := -1
+
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("panic during filtering: %v", r)
+			if current >= 0 {
+				obs.panicked(&data[current], r)
+			}
+			err = recoveredError(r, "panic during filtering")
 		}
+		obs.complete(err)
 	}()
 
-	if query == nil {
-		if skip == 0 && (limit == 0 || limit >= len(data)) {
-			return data, nil
+	done := ctx.Done()
+	count := 0
+	for i := range data {
+		select {
+		case <-done:
+			return result, ctx.Err()
+		default:
 		}
-		return data[min(skip, len(data)):min(skip+limit, len(data))], nil
-	}
 
-	count := 0
-	for _, item := range data {
-		if eval(query, item) {
+		current = i
+		start := obs.begin()
+		matches := match(i)
+		obs.evaluated(&data[i], matches, start)
+
+		if matches {
 			if count < skip {
 				count++
+				obs.skipped(&data[i])
 				continue
 			}
 
-			result = append(result, item)
+			result = append(result, data[i])
+			obs.returned()
 
 			if limit > 0 && len(result) >= limit {
+				obs.limitReached(limit)
 				break
 			}
 		}
@@ -48,42 +123,76 @@ func Filter[T any](data []T, query Query, skip int, limit int) (result []T, err
 	return result, err
 }
 
-// FilterC filters data based on any query type (like Filter but with channel io)
-func FilterC[T any](input <-chan T, query Query, skip int, limit int) (<-chan T, <-chan error) {
+// filterChan streams the items of input for which match is true, obs completes once it's done
+func filterChan[T any](ctx context.Context, input <-chan T, match func(T) bool, skip int, limit int, obs *observer[T]) (<-chan T, <-chan error) {
 	output := make(chan T)
-	errCh := make(chan error)
+	errCh := make(chan error, 1)
 
 	go func() {
+		var err error
+
 		defer close(output)
 		defer close(errCh)
+		defer func() {
+			obs.complete(err)
+		}()
 
 		matched := 0
 		sent := 0
 
-		for item := range input {
+		for {
+			var item T
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				reportCtxErr(ctx, errCh)
+				return
+			case v, ok := <-input:
+				if !ok {
+					return
+				}
+				item = v
+			}
+
 			var matches bool
 			func() {
 				defer func() {
 					if r := recover(); r != nil {
-						errCh <- fmt.Errorf("panic during filter evaluation: %v", r)
+						obs.panicked(&item, r)
+						select {
+						case errCh <- recoveredError(r, "panic during filter evaluation"):
+						case <-ctx.Done():
+						}
 						matches = false
 					}
 				}()
-				matches = eval(query, item)
+				start := obs.begin()
+				matches = match(item)
+				obs.evaluated(&item, matches, start)
 			}()
 
 			if matches {
 				matched++
 
 				if matched <= skip {
+					obs.skipped(&item)
 					continue
 				}
-				if limit > 0 && sent >= limit {
+
+				select {
+				case output <- item:
+				case <-ctx.Done():
+					err = ctx.Err()
+					reportCtxErr(ctx, errCh)
 					return
 				}
-
-				output <- item
 				sent++
+				obs.returned()
+
+				if limit > 0 && sent >= limit {
+					obs.limitReached(limit)
+					return
+				}
 			}
 		}
 	}()
@@ -91,6 +200,39 @@ func FilterC[T any](input <-chan T, query Query, skip int, limit int) (<-chan T,
 	return output, errCh
 }
 
+// evalError carries an evaluation error out of a query as a panic,
+// filtering functions recover it and report err as is
+type evalError struct {
+	err error
+}
+
+// recoveredError converts a value recovered from a panic during evaluation into an error
+func recoveredError(r interface{}, msg string) error {
+	if e, ok := r.(evalError); ok {
+		return e.err
+	}
+	return fmt.Errorf("%s: %v", msg, r)
+}
+
+// failedC returns closed channels carrying only err
+func failedC[T any](err error) (<-chan T, <-chan error) {
+	output := make(chan T)
+	errCh := make(chan error, 1)
+	errCh <- err
+	close(output)
+	close(errCh)
+	return output, errCh
+}
+
+// reportCtxErr sends ctx.Err() on errCh without blocking, so an abandoned
+// error channel can't keep the filtering goroutine alive.
+func reportCtxErr(ctx context.Context, errCh chan<- error) {
+	select {
+	case errCh <- ctx.Err():
+	default:
+	}
+}
+
 // eval checks if a value satisfies a query of any type
 func eval(query Query, value interface{}) bool {
 	switch q := query.(type) {
@@ -128,34 +270,31 @@ func evalMapQuery(item interface{}, query Q) bool {
 	return true
 }
 
+// getField resolves a field name or dot path (see splitPath) against item
 func getField(item interface{}, fieldName string) interface{} {
+	return fieldOf(item, fieldName, false)
+}
+
+// fieldOf resolves a field name or dot path against item like getField, struct fields
+// being resolved by tag names too with tagNames (see WithTagNames)
+func fieldOf(item interface{}, fieldName string, tagNames bool) interface{} {
 	if item == nil {
 		return nil
 	}
 
-	itemVal := reflect.ValueOf(item)
-
-	if itemVal.Kind() == reflect.Ptr {
-		itemVal = itemVal.Elem()
+	value := resolvePath(reflect.ValueOf(item), fieldName, tagNames)
+	if !value.IsValid() || !value.CanInterface() {
+		return nil
 	}
+	return value.Interface()
+}
 
-	switch itemVal.Kind() {
-	case reflect.Map:
-		keyVal := reflect.ValueOf(fieldName)
-		valueVal := itemVal.MapIndex(keyVal)
-		if !valueVal.IsValid() {
-			return nil
-		}
-		return valueVal.Interface()
-
-	case reflect.Struct:
-		field := itemVal.FieldByName(fieldName)
-		if !field.IsValid() {
-			return nil
-		}
-		return field.Interface()
-
-	default:
-		return nil
+// fieldOrItem resolves field against item, an empty field being the item itself.
+// Records are resolved against their Value (see Record).
+func fieldOrItem(item interface{}, field string) interface{} {
+	item = recordValue(item)
+	if field == "" {
+		return item
 	}
+	return getField(item, field)
 }
@@ -1,6 +1,10 @@
 package fq
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
 	"strconv"
 	"testing"
 	"time"
@@ -476,6 +480,232 @@ func TestNestedObjectQueries(t *testing.T) {
 	}
 }
 
+func TestFieldPaths(t *testing.T) {
+	products := getTestProducts()
+
+	// Struct fields through dot paths
+	result, err := Filter(products, Q{
+		"Address.City":         Contains("Angeles"),
+		"Manufacturer.Country": "USA",
+	}, 0, 0)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(result) != 1 || result[0].ID != 4 {
+		t.Errorf("Expected 1 product in Los Angeles, got %v", result)
+	}
+
+	// Map keys and slice indexes
+	result, err = Filter(products, Q{
+		"Properties.warranty": Gt(1),
+		"Tags.0":              "premium",
+	}, 0, 0)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("Expected 2 premium products with warranty > 1, got %d", len(result))
+	}
+
+	// Out of range indexes resolve to nil
+	result, err = Filter(products, Q{
+		"Tags.5": nil,
+	}, 0, 0)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != len(products) {
+		t.Errorf("Expected all products to have no 6th tag, got %d", len(result))
+	}
+
+	// Pointers, nested maps and escaped dots
+	type Order struct {
+		Customer *Address
+		Meta     map[string]interface{}
+	}
+
+	orders := []Order{
+		{
+			Customer: &Address{City: "Paris"},
+			Meta: map[string]interface{}{
+				"app.version": "1.2",
+				"items":       []interface{}{map[string]interface{}{"sku": "A-1"}},
+			},
+		},
+		{
+			Meta: map[string]interface{}{"app": map[string]interface{}{"version": "2.0"}},
+		},
+	}
+
+	orderResult, err := Filter(orders, Q{"Customer.City": "Paris"}, 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(orderResult) != 1 {
+		t.Errorf("Expected 1 order from Paris, got %d", len(orderResult))
+	}
+
+	orderResult, err = Filter(orders, Q{"Meta.items.0.sku": "A-1"}, 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(orderResult) != 1 {
+		t.Errorf("Expected 1 order with sku A-1, got %d", len(orderResult))
+	}
+
+	orderResult, err = Filter(orders, Q{`Meta.app\.version`: "1.2"}, 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(orderResult) != 1 || orderResult[0].Customer == nil {
+		t.Errorf("Expected escaped key to match the first order, got %v", orderResult)
+	}
+
+	orderResult, err = Filter(orders, Q{"Meta.app.version": "2.0"}, 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(orderResult) != 1 || orderResult[0].Customer != nil {
+		t.Errorf("Expected nested key to match the second order, got %v", orderResult)
+	}
+
+	// Keys with literal dots match without escaping, before the path is split
+	orderResult, err = Filter(orders, Q{"Meta.app.version": "1.2"}, 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(orderResult) != 1 || orderResult[0].Customer == nil {
+		t.Errorf("Expected the literal key to match the first order, got %v", orderResult)
+	}
+
+	events := []map[string]interface{}{{"app.version": "1.0"}, {"app": map[string]interface{}{"version": "1.0"}}}
+	eventResult, err := Filter(events, Q{"app.version": "1.0"}, 0, 0)
+	if err != nil || len(eventResult) != 2 {
+		t.Errorf("Expected both the literal key and the path to match, got %v (%v)", eventResult, err)
+	}
+
+	compiled, err := Compile[Order](Q{"Meta.app.version": "1.2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !compiled.Match(orders[0]) || compiled.Match(orders[1]) {
+		t.Error("Expected the compiled literal key to match the first order only")
+	}
+
+	counts, err := Compile[map[string]map[string]int](Q{"a.b.c": 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !counts.Match(map[string]map[string]int{"a": {"b.c": 1}}) || counts.Match(map[string]map[string]int{"a": {"b": 1}}) {
+		t.Error("Expected the compiled literal key of a nested map to match")
+	}
+	versions, err := Compile[map[string]int](Q{"app.version": 2})
+	if err != nil || !versions.Match(map[string]int{"app.version": 2}) {
+		t.Errorf("Expected the compiled literal key to match, got %v", err)
+	}
+}
+
+func TestStructTags(t *testing.T) {
+	type Audit struct {
+		CreatedBy string `json:"created_by"`
+		UpdatedBy string `json:"updated_by"`
+	}
+
+	type Meta struct {
+		Source string `json:"source"`
+	}
+
+	type Event struct {
+		Audit
+		*Meta
+		Extra     Meta      `json:"extra"`
+		ID        int       `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+		Kind      string    `json:"type" fq:"kind"`
+		Category  string    `json:"kind"`
+		Internal  string    `json:"-"`
+		UpdatedBy string
+	}
+
+	baseTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Audit: Audit{CreatedBy: "ana", UpdatedBy: "bob"}, Meta: &Meta{Source: "api"}, ID: 1, CreatedAt: baseTime, Kind: "click", Category: "web", UpdatedBy: "carl"},
+		{Audit: Audit{CreatedBy: "bob"}, Extra: Meta{Source: "batch"}, ID: 2, CreatedAt: baseTime.AddDate(0, 1, 0), Kind: "view", Category: "click"},
+	}
+
+	tests := []struct {
+		name     string
+		query    Q
+		expected []int
+	}{
+		{"json tag", Q{"created_at": Gt(baseTime)}, []int{2}},
+		{"go name still works", Q{"CreatedAt": baseTime}, []int{1}},
+		{"fq tag", Q{"kind": "click"}, []int{1}},
+		{"fq tag wins over json tag", Q{"kind": "click"}, []int{1}},
+		{"json tag of a field with an fq tag", Q{"type": "view"}, []int{2}},
+		{"promoted from embedded struct", Q{"created_by": "bob"}, []int{2}},
+		{"promoted through embedded pointer", Q{"source": "api"}, []int{1}},
+		{"tagged struct field with path", Q{"extra.source": "batch"}, []int{2}},
+		{"shallower field hides promoted one", Q{"UpdatedBy": "carl"}, []int{1}},
+		{"json tag of hidden field", Q{"updated_by": "bob"}, []int{1}},
+		{"excluded from json", Q{"Internal": ""}, []int{1, 2}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Filter(events, tc.query, 0, 0, WithTagNames())
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			var ids []int
+			for _, e := range result {
+				ids = append(ids, e.ID)
+			}
+			if len(ids) != len(tc.expected) {
+				t.Fatalf("Expected ids %v, got %v", tc.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tc.expected[i] {
+					t.Errorf("Expected ids %v, got %v", tc.expected, ids)
+				}
+			}
+		})
+	}
+
+	t.Run("go names only by default", func(t *testing.T) {
+		var unknown *ErrUnknownField
+		if _, err := Filter(events, Q{"created_at": Gt(baseTime)}, 0, 0); !errors.As(err, &unknown) {
+			t.Errorf("Filter: expected an unknown field error, got %v", err)
+		}
+		if err := Validate(Q{"kind": "click"}, reflect.TypeOf(Event{})); !errors.As(err, &unknown) {
+			t.Errorf("Validate: expected an unknown field error, got %v", err)
+		}
+		if err := Validate(Q{"kind": "click"}, reflect.TypeOf(Event{}), WithTagNames()); err != nil {
+			t.Errorf("Validate: unexpected error with tag names: %v", err)
+		}
+
+		compiled, err := Compile[Event](Q{"type": "view"}, WithTagNames())
+		if err != nil {
+			t.Fatalf("Compile: unexpected error: %v", err)
+		}
+		if compiled.Match(events[0]) || !compiled.Match(events[1]) {
+			t.Error("Compile: expected the json tag to match the second event only")
+		}
+
+		if e := Explain(Q{"kind": "click"}, events[0]); e.Result {
+			t.Errorf("Explain: expected the fq tag not to resolve, got %s", e)
+		}
+		if e := Explain(Q{"kind": "click"}, events[0], WithTagNames()); !e.Result {
+			t.Errorf("Explain: expected the fq tag to resolve, got %s", e)
+		}
+	})
+}
+
 // Custom Function Tests --------------------------------------------------
 
 func TestCustomFunctions(t *testing.T) {
@@ -600,6 +830,259 @@ func TestPagination(t *testing.T) {
 	}
 }
 
+// Context Tests ----------------------------------------------------------
+
+func TestFilterCtx(t *testing.T) {
+	products := getTestProducts()
+
+	// Live context behaves like Filter
+	result, err := FilterCtx(context.Background(), products, Q{"InStock": true}, 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != 4 {
+		t.Errorf("Expected 4 products in stock, got %d", len(result))
+	}
+
+	// Canceled context stops before evaluating anything
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	evaluated := 0
+	result, err = FilterCtx(ctx, products, func(v interface{}) bool {
+		evaluated++
+		return true
+	}, 0, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if evaluated != 0 || len(result) != 0 {
+		t.Errorf("Expected no evaluation after cancel, got %d evaluated, %d results", evaluated, len(result))
+	}
+
+	// Cancellation mid-way returns the partial result
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err = FilterCtx(ctx, products, func(v interface{}) bool {
+		if v.(Product).ID == 2 {
+			cancel()
+		}
+		return true
+	}, 0, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("Expected 2 partial results, got %d", len(result))
+	}
+}
+
+func TestFilterCCtx(t *testing.T) {
+	t.Run("deadline_exceeded", func(t *testing.T) {
+		input := make(chan int) // never sends, never closes
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		dataCh, errCh := FilterCCtx(ctx, input, Gt(0), 0, 0)
+
+		for range dataCh {
+			t.Error("Expected no results")
+		}
+		err, ok := <-errCh
+		if !ok || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+		if _, ok := <-errCh; ok {
+			t.Error("Expected error channel to be closed")
+		}
+	})
+
+	t.Run("abandoned_consumer", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		input := make(chan int)
+		go func() {
+			for i := 1; ; i++ {
+				select {
+				case input <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		dataCh, errCh := FilterCCtx(ctx, input, Gt(0), 0, 0)
+
+		if v := <-dataCh; v != 1 {
+			t.Errorf("Expected first item 1, got %d", v)
+		}
+
+		// stop reading from both channels, the goroutine must still exit
+		cancel()
+
+		select {
+		case <-drained(dataCh):
+		case <-time.After(time.Second):
+			t.Fatal("Output channel was not closed after cancel")
+		}
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("limit_closes_output", func(t *testing.T) {
+		input := make(chan int) // stays open
+		defer close(input)
+
+		dataCh, _ := FilterCCtx(context.Background(), input, Gt(0), 0, 1)
+		input <- 1
+
+		if v := <-dataCh; v != 1 {
+			t.Errorf("Expected first item 1, got %d", v)
+		}
+		select {
+		case <-drained(dataCh):
+		case <-time.After(time.Second):
+			t.Fatal("Output channel was not closed after reaching the limit")
+		}
+	})
+}
+
+// drained closes the returned channel once ch is closed
+func drained[T any](ch <-chan T) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
+
+// Hooks Tests ------------------------------------------------------------
+
+func TestHooks(t *testing.T) {
+	products := getTestProducts()
+
+	var evaluated, matched, skipped []int
+	var limitReached []int
+	var stats []Stats
+
+	hooks := Hooks{
+		OnItemEvaluated: func(item interface{}, ok bool, d time.Duration) {
+			evaluated = append(evaluated, item.(Product).ID)
+		},
+		OnMatch: func(item interface{}) {
+			matched = append(matched, item.(Product).ID)
+		},
+		OnSkip: func(item interface{}) {
+			skipped = append(skipped, item.(Product).ID)
+		},
+		OnLimitReached: func(limit int) {
+			limitReached = append(limitReached, limit)
+		},
+		OnComplete: func(s Stats) {
+			stats = append(stats, s)
+		},
+	}
+
+	result, err := Filter(products, Q{"Price": Gt(200)}, 1, 2, WithHooks(hooks))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != 2 || result[0].ID != 2 || result[1].ID != 3 {
+		t.Errorf("Expected products 2 and 3, got %v", result)
+	}
+
+	if len(evaluated) != 3 || len(matched) != 3 || len(skipped) != 1 || skipped[0] != 1 {
+		t.Errorf("Unexpected hook calls: evaluated %v, matched %v, skipped %v", evaluated, matched, skipped)
+	}
+	if len(limitReached) != 1 || limitReached[0] != 2 {
+		t.Errorf("Expected OnLimitReached(2) once, got %v", limitReached)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("Expected OnComplete once, got %d", len(stats))
+	}
+	if s := stats[0]; s.Evaluated != 3 || s.Matched != 3 || s.Skipped != 1 || s.Returned != 2 || s.Err != nil || s.Duration <= 0 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+
+	// panics
+	var panics []interface{}
+	stats = nil
+	_, err = Filter(products, func(v interface{}) bool {
+		panic("boom")
+	}, 0, 0, WithHooks(Hooks{
+		OnPanic: func(item interface{}, recovered interface{}) {
+			panics = append(panics, recovered)
+		},
+		OnComplete: hooks.OnComplete,
+	}))
+	if err == nil {
+		t.Error("Expected an error from a panicking predicate")
+	}
+	if len(panics) != 1 || panics[0] != "boom" {
+		t.Errorf("Expected OnPanic once with the recovered value, got %v", panics)
+	}
+	if len(stats) != 1 || stats[0].Evaluated != 1 || stats[0].Panics != 1 || stats[0].Err != err {
+		t.Errorf("Unexpected stats after panic: %+v", stats)
+	}
+
+	// a panicking hook counts the item once
+	stats = nil
+	_, err = Filter(products, Q{"InStock": true}, 0, 0, WithHooks(Hooks{
+		OnItemEvaluated: func(item interface{}, ok bool, d time.Duration) {
+			panic("hook")
+		},
+		OnComplete: hooks.OnComplete,
+	}))
+	if err == nil || len(stats) != 1 || stats[0].Evaluated != 1 || stats[0].Panics != 1 {
+		t.Errorf("Unexpected stats after a hook panic: %+v, %v", stats, err)
+	}
+
+	// calls ending before filtering complete too
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	invalid := Q{"Price": Gt([]int{1})}
+	complete := WithHooks(Hooks{OnComplete: hooks.OnComplete})
+	stats = nil
+
+	FilterCtx(canceled, products, Q{"InStock": true}, 0, 0, complete)
+	Filter(products, invalid, 0, 0, complete)
+	_, errCh := FilterC(make(chan Product), invalid, 0, 0, complete)
+	<-errCh
+	_, errCh = FilterCParallel(make(chan Product), invalid, 2, true, 0, 0, complete)
+	<-errCh
+	for range FilterSeq2(withErrors(slices.Values(products)), invalid, 0, 0, complete) {
+	}
+
+	if len(stats) != 5 {
+		t.Fatalf("Expected OnComplete for each call, got %+v", stats)
+	}
+	for _, s := range stats {
+		if s.Err == nil || s.Evaluated != 0 {
+			t.Errorf("Expected stats with the error and no evaluation, got %+v", s)
+		}
+	}
+
+	// streaming
+	stats = nil
+	input := make(chan Product, len(products))
+	for _, product := range products {
+		input <- product
+	}
+	close(input)
+
+	dataCh, errCh := FilterC(input, Q{"InStock": true}, 0, 0, WithHooks(Hooks{OnComplete: hooks.OnComplete}))
+	results, errs := collectResults(chanToInterface(dataCh), errCh)
+	if len(results) != 4 || len(errs) != 0 {
+		t.Errorf("Expected 4 results without errors, got %d and %v", len(results), errs)
+	}
+	if len(stats) != 1 || stats[0].Evaluated != 5 || stats[0].Matched != 4 || stats[0].Returned != 4 {
+		t.Errorf("Unexpected streaming stats: %+v", stats)
+	}
+}
+
 // Edge Cases Tests -------------------------------------------------------
 
 func TestEdgeCases(t *testing.T) {
@@ -0,0 +1,293 @@
+package fq
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"time"
+)
+
+// defaultPollInterval is how often a followed file is checked for new lines
+const defaultPollInterval = 250 * time.Millisecond
+
+// followPolled is called when a followed file is read to its end, before waiting for new
+// lines. Tests set it to step followed sources.
+var followPolled func()
+
+// FromStart makes FollowJSONLSource read the lines already in the file before following
+// it. Only the lines appended once following starts are read by default, like tail -f.
+func FromStart() SourceOption {
+	return func(o *sourceOptions) {
+		o.fromStart = true
+	}
+}
+
+// WithPollInterval sets how often FollowJSONLSource checks the file for new lines once it
+// read them all, 250ms by default
+func WithPollInterval(d time.Duration) SourceOption {
+	return func(o *sourceOptions) {
+		o.pollInterval = d
+	}
+}
+
+// FollowJSONLSource creates a channel of the objects parsed from the lines appended to a
+// JSONL file, like tail -F, and a channel for errors. It keeps reading until ctx is done,
+// then reports ctx.Err() on the error channel and closes both channels, even if the consumer
+// is no longer reading from them. A file truncated or replaced by a new file at filePath,
+// as log rotation does, is read again from its start. A line is only parsed once its line
+// ending is written. The file isn't decompressed.
+func FollowJSONLSource(ctx context.Context, filePath string, opts ...SourceOption) (<-chan interface{}, <-chan error) {
+	output := make(chan interface{}, 100)
+	errCh := make(chan error, 10)
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		for obj, err := range FollowJSONLSourceSeq(ctx, filePath, opts...) {
+			if err != nil {
+				select {
+				case errCh <- err:
+				case <-ctx.Done():
+					reportCtxErr(ctx, errCh)
+					return
+				}
+				continue
+			}
+			select {
+			case output <- obj:
+			case <-ctx.Done():
+				reportCtxErr(ctx, errCh)
+				return
+			}
+		}
+	}()
+
+	return output, errCh
+}
+
+// FollowJSONLSourceSeq iterates over the objects parsed from the lines appended to a JSONL
+// file like FollowJSONLSource, yielding errors along with a nil object. It ends by yielding
+// ctx.Err() once ctx is done. Lines are numbered from the first line read.
+func FollowJSONLSourceSeq(ctx context.Context, filePath string, opts ...SourceOption) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		o := newSourceOptions(opts)
+		if o.pollInterval <= 0 {
+			o.pollInterval = defaultPollInterval
+		}
+
+		err := followJSONL(ctx, filePath, o, func(obj interface{}, _ int, _ int64, err error) bool {
+			return yield(obj, err)
+		})
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// FollowJSONLRecordSeq iterates over the Records of the objects parsed from the lines appended
+// to a JSONL file like FollowJSONLSourceSeq, yielding errors along with the zero Record. Lines
+// are numbered from the first line read, and from the start of the file once it's rotated.
+func FollowJSONLRecordSeq(ctx context.Context, filePath string, opts ...SourceOption) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		o := newSourceOptions(opts)
+		if o.pollInterval <= 0 {
+			o.pollInterval = defaultPollInterval
+		}
+
+		err := followJSONL(ctx, filePath, o, func(obj interface{}, line int, offset int64, err error) bool {
+			if err != nil {
+				return yield(Record{}, err)
+			}
+			return yield(Record{Value: obj, File: filePath, Line: line, Offset: offset}, nil)
+		})
+		if err != nil {
+			yield(Record{}, err)
+		}
+	}
+}
+
+// followJSONL yields the objects decoded from the lines appended to the file along with their
+// line number and the byte offset of the line, like readPositions, until yield returns false
+// or ctx is done, returning ctx.Err() or the open or read error if any
+func followJSONL(ctx context.Context, filePath string, o *sourceOptions, yield func(obj interface{}, line int, offset int64, err error) bool) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		file.Close()
+	}()
+
+	lines := &lineSplitter{o: o}
+	var offset int64
+	if !o.fromStart {
+		if offset, err = file.Seek(0, io.SeekEnd); err != nil {
+			return fmt.Errorf("error reading file: %w", err)
+		}
+		// the last line may be half written
+		lines.partial, err = endsMidLine(file, offset)
+		if err != nil {
+			return fmt.Errorf("error reading file: %w", err)
+		}
+		lines.offset = offset
+	}
+
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+	buf := make([]byte, 32*1024)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := file.Read(buf)
+		offset += int64(n)
+		if !lines.write(buf[:n], yield) {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("error reading file: %w", err)
+		}
+		if n > 0 {
+			continue
+		}
+
+		// all lines are read, look for a rotation before waiting for new ones
+		info, err := os.Stat(filePath)
+		current, statErr := file.Stat()
+		switch {
+		case err != nil || statErr != nil:
+			// renamed and not created again yet
+		case !os.SameFile(info, current):
+			reopened, err := os.Open(filePath)
+			if err == nil {
+				file.Close()
+				file, offset = reopened, 0
+				lines.reset()
+				continue
+			}
+			if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to open file: %w", err)
+			}
+		case info.Size() < offset:
+			if offset, err = file.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("error reading file: %w", err)
+			}
+			lines.reset()
+			continue
+		}
+
+		if followPolled != nil {
+			followPolled()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// endsMidLine reports whether the content of file before offset doesn't end with a line ending
+func endsMidLine(file *os.File, offset int64) (bool, error) {
+	if offset == 0 {
+		return false, nil
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, offset-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+// lineSplitter decodes the lines of the chunks written to it, keeping the last line of a
+// chunk until its line ending is written
+type lineSplitter struct {
+	o       *sourceOptions
+	pending []byte
+	tooLong bool
+	// partial drops the pending line, read from its middle
+	partial bool
+	lineNum int
+	// offset is the offset of the pending line in the file, size the bytes of it written so far
+	offset int64
+	size   int
+}
+
+// write yields the objects decoded from the lines completed by chunk, returning false once
+// yield returns false
+func (l *lineSplitter) write(chunk []byte, yield func(obj interface{}, line int, offset int64, err error) bool) bool {
+	for len(chunk) > 0 {
+		end := bytes.IndexByte(chunk, '\n')
+		part := chunk
+		if end >= 0 {
+			part = chunk[:end+1]
+		}
+		l.size += len(part)
+		part = bytes.TrimSuffix(part, []byte("\n"))
+
+		if !l.tooLong && !l.partial {
+			l.pending = append(l.pending, part...)
+			// keep room for a \r ending until the line is complete
+			if l.o.maxLineSize > 0 && len(l.pending) > l.o.maxLineSize+1 {
+				l.tooLong = true
+				l.pending = l.pending[:0]
+			}
+		}
+		if end < 0 {
+			return true
+		}
+		chunk = chunk[end+1:]
+
+		if !l.emit(yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// emit yields the object decoded from the pending line, returning false once yield returns false
+func (l *lineSplitter) emit(yield func(obj interface{}, line int, offset int64, err error) bool) bool {
+	line := bytes.TrimSuffix(l.pending, []byte("\r"))
+	tooLong := l.tooLong || l.o.maxLineSize > 0 && len(line) > l.o.maxLineSize
+	partial := l.partial
+	offset := l.offset
+	l.pending = l.pending[:0]
+	l.tooLong = false
+	l.partial = false
+	l.offset += int64(l.size)
+	l.size = 0
+
+	if partial {
+		return true
+	}
+	l.lineNum++
+
+	if tooLong {
+		return yield(nil, l.lineNum, offset, fmt.Errorf("line %d: %w (max %d bytes)", l.lineNum, ErrLineTooLong, l.o.maxLineSize))
+	}
+	if len(bytes.TrimSpace(line)) == 0 {
+		return true
+	}
+
+	obj, err := decodeLine[interface{}](line, l.o)
+	if err != nil {
+		return yield(nil, l.lineNum, offset, fmt.Errorf("line %d: error parsing JSON: %w", l.lineNum, err))
+	}
+	return yield(obj, l.lineNum, offset, nil)
+}
+
+// reset drops the pending line and numbers lines from the start of a new file
+func (l *lineSplitter) reset() {
+	l.pending = l.pending[:0]
+	l.tooLong = false
+	l.partial = false
+	l.lineNum = 0
+	l.offset = 0
+	l.size = 0
+}
@@ -0,0 +1,221 @@
+package fq
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receive returns the next object or error of a followed source, failing after a second
+func receive(t *testing.T, output <-chan interface{}, errCh <-chan error) (interface{}, error) {
+	t.Helper()
+	select {
+	case obj := <-output:
+		return obj, nil
+	case err := <-errCh:
+		return nil, err
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a line")
+		return nil, nil
+	}
+}
+
+func appendFile(t *testing.T, path string, content string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+}
+
+// follower reads a followed source step by step: the source waits for the test at the end
+// of the file, so the test knows what it read before writing more
+type follower struct {
+	t       *testing.T
+	results chan followResult
+	polls   chan struct{}
+}
+
+type followResult struct {
+	record Record
+	err    error
+}
+
+// follow follows the Records of path until ctx is done, one follower at a time
+func follow(t *testing.T, ctx context.Context, path string, opts ...SourceOption) *follower {
+	f := &follower{t: t, results: make(chan followResult), polls: make(chan struct{})}
+	followPolled = func() {
+		select {
+		case f.polls <- struct{}{}:
+		case <-ctx.Done():
+		}
+	}
+	done := make(chan struct{})
+	// the subtests cancel ctx before their cleanup
+	t.Cleanup(func() {
+		<-done
+		followPolled = nil
+	})
+
+	// one channel keeps objects and errors in order
+	go func() {
+		defer close(done)
+		for record, err := range FollowJSONLRecordSeq(ctx, path, append(opts, WithPollInterval(time.Millisecond))...) {
+			select {
+			case f.results <- followResult{record, err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return f
+}
+
+// caughtUp waits until the source read the file to its end, including what was written
+// before the call: the first poll may have started before the writes, the second can't
+func (f *follower) caughtUp() {
+	f.t.Helper()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-f.polls:
+		case r := <-f.results:
+			f.t.Fatalf("Unexpected line %v, %v", r.record.Value, r.err)
+		case <-time.After(time.Second):
+			f.t.Fatal("Timed out waiting for the source to read the file")
+		}
+	}
+}
+
+// expect checks the Value of the next Record, or the next error if wantErr isn't empty,
+// returning the Record
+func (f *follower) expect(want interface{}, wantErr string) Record {
+	f.t.Helper()
+	var r followResult
+	for received := false; !received; {
+		select {
+		case r = <-f.results:
+			received = true
+		case <-f.polls:
+		case <-time.After(time.Second):
+			f.t.Fatal("Timed out waiting for a line")
+		}
+	}
+
+	if wantErr != "" {
+		if r.err == nil || !strings.Contains(r.err.Error(), wantErr) {
+			f.t.Fatalf("Expected error %q, got %v, %v", wantErr, r.record.Value, r.err)
+		}
+		return r.record
+	}
+	if r.err != nil || !reflect.DeepEqual(r.record.Value, want) {
+		f.t.Fatalf("Expected %v, got %v, %v", want, r.record.Value, r.err)
+	}
+	return r.record
+}
+
+// expectPosition checks the line number and the offset of record
+func expectPosition(t *testing.T, record Record, line int, offset int64) {
+	t.Helper()
+	if record.Line != line || record.Offset != offset {
+		t.Errorf("Expected line %d at offset %d, got line %d at offset %d", line, offset, record.Line, record.Offset)
+	}
+}
+
+func TestFollowJSONLSource(t *testing.T) {
+	t.Run("from the end", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendFile(t, path, "{\"id\": 0}\n{\"id\": 0, \"half\":")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		f := follow(t, ctx, path)
+		f.caughtUp()
+
+		// the end of the half line is dropped, not reported as invalid JSON,
+		// lines are numbered from the first line read
+		appendFile(t, path, " true}\n{\"id\": 1}\n")
+		record := f.expect(map[string]interface{}{"id": 1.0}, "")
+		expectPosition(t, record, 1, 34)
+		if record.File != path {
+			t.Errorf("Expected file %s, got %s", path, record.File)
+		}
+		appendFile(t, path, "{\"id\": 2}\n")
+		expectPosition(t, f.expect(map[string]interface{}{"id": 2.0}, ""), 2, 44)
+		f.caughtUp()
+	})
+
+	t.Run("rotation and partial lines", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.log")
+		appendFile(t, path, "{\"id\": 1}\n\n{\"id\": 2, \"padding\": \""+strings.Repeat("x", 100)+"\"}\n")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		f := follow(t, ctx, path, FromStart(), WithMaxLineSize(64))
+
+		expectPosition(t, f.expect(map[string]interface{}{"id": 1.0}, ""), 1, 0)
+		f.expect(nil, "line 3: line too long")
+		f.caughtUp()
+
+		// truncated
+		if err := os.WriteFile(path, []byte("{\"id\": 3}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		expectPosition(t, f.expect(map[string]interface{}{"id": 3.0}, ""), 1, 0)
+
+		appendFile(t, path, "{\"id\":")
+		f.caughtUp()
+		appendFile(t, path, " 4}\r\n{invalid}\n")
+		expectPosition(t, f.expect(map[string]interface{}{"id": 4.0}, ""), 2, 10)
+		f.expect(nil, "line 3: error parsing JSON")
+
+		// renamed, then created again
+		if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+			t.Fatalf("Failed to rename test file: %v", err)
+		}
+		f.caughtUp()
+		appendFile(t, path, "{\"id\": 5}\n")
+		expectPosition(t, f.expect(map[string]interface{}{"id": 5.0}, ""), 1, 0)
+		f.caughtUp()
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendFile(t, path, "{\"id\": 1}\n")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		output, errCh := FollowJSONLSource(ctx, path, FromStart(), WithPollInterval(5*time.Millisecond))
+		if obj, err := receive(t, output, errCh); err != nil || obj == nil {
+			t.Fatalf("Expected a line, got %v, %v", obj, err)
+		}
+		cancel()
+
+		var errs []error
+		for err := range errCh {
+			errs = append(errs, err)
+		}
+		if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", errs)
+		}
+		if _, ok := <-output; ok {
+			t.Error("Expected the output channel to be closed")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		for _, err := range FollowJSONLSourceSeq(context.Background(), filepath.Join(t.TempDir(), "missing.log")) {
+			if err == nil || !strings.Contains(err.Error(), "failed to open file") {
+				t.Errorf("Expected an open error, got %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package fq
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"reflect"
+)
+
+// Aggregator computes a value over the items of a group (see GroupBy)
+type Aggregator struct {
+	name  string
+	field string
+	new   func() accumulator
+}
+
+// accumulator is the state of an Aggregator for a group
+type accumulator interface {
+	add(v interface{})
+	result() interface{}
+}
+
+// Name returns the key of the aggregated value in grouped rows
+func (a Aggregator) Name() string {
+	return a.name
+}
+
+// As returns the aggregator with its value keyed by name in grouped rows
+func (a Aggregator) As(name string) Aggregator {
+	a.name = name
+	return a
+}
+
+// Count counts the items of a group, keyed by "count"
+func Count() Aggregator {
+	return Aggregator{name: "count", new: func() accumulator { return &countAcc{} }}
+}
+
+// Sum adds up the numeric values of field, keyed by "sum_<field>".
+// Non-numeric values are ignored.
+func Sum(field string) Aggregator {
+	return Aggregator{name: "sum_" + field, field: field, new: func() accumulator { return &sumAcc{} }}
+}
+
+// Avg averages the numeric values of field, keyed by "avg_<field>".
+// Non-numeric values are ignored, groups without any average to nil.
+func Avg(field string) Aggregator {
+	return Aggregator{name: "avg_" + field, field: field, new: func() accumulator { return &avgAcc{} }}
+}
+
+// Min returns the smallest value of field (ordered like in Sort), keyed by "min_<field>".
+// nil values are ignored.
+func Min(field string) Aggregator {
+	return Aggregator{name: "min_" + field, field: field, new: func() accumulator { return &extremeAcc{sign: -1} }}
+}
+
+// Max returns the largest value of field (ordered like in Sort), keyed by "max_<field>".
+// nil values are ignored.
+func Max(field string) Aggregator {
+	return Aggregator{name: "max_" + field, field: field, new: func() accumulator { return &extremeAcc{sign: 1} }}
+}
+
+type countAcc struct {
+	n int
+}
+
+func (c *countAcc) add(interface{})     { c.n++ }
+func (c *countAcc) result() interface{} { return c.n }
+
+type sumAcc struct {
+	sum float64
+}
+
+func (s *sumAcc) add(v interface{}) {
+	if num, ok := toNumber(v); ok {
+		s.sum += num
+	}
+}
+
+func (s *sumAcc) result() interface{} { return s.sum }
+
+type avgAcc struct {
+	sum float64
+	n   int
+}
+
+func (a *avgAcc) add(v interface{}) {
+	if num, ok := toNumber(v); ok {
+		a.sum += num
+		a.n++
+	}
+}
+
+func (a *avgAcc) result() interface{} {
+	if a.n == 0 {
+		return nil
+	}
+	return a.sum / float64(a.n)
+}
+
+// extremeAcc keeps the smallest (sign -1) or largest (sign 1) value
+type extremeAcc struct {
+	sign  int
+	value interface{}
+}
+
+func (e *extremeAcc) add(v interface{}) {
+	if v == nil {
+		return
+	}
+	if e.value == nil || orderValues(v, e.value)*e.sign > 0 {
+		e.value = v
+	}
+}
+
+func (e *extremeAcc) result() interface{} { return e.value }
+
+// GroupBy groups data by the value of key (a field name or dot path) and aggregates each group.
+// It returns a row per group, in the order groups are first seen, holding the group value
+// under key and the aggregated values under their names.
+// Numbers group by value whatever their type, so 1 and 1.0 end up in the same group.
+func GroupBy[T any](data []T, key string, aggs ...Aggregator) []map[string]interface{} {
+	g := newGrouper[T](key, aggs)
+	for i := range data {
+		g.add(data[i])
+	}
+	return g.rows()
+}
+
+// GroupByC groups the items of input like GroupBy, sending the rows once input is closed.
+// It only keeps a row per group in memory, so it can aggregate the output of FilterC.
+func GroupByC[T any](input <-chan T, key string, aggs ...Aggregator) <-chan map[string]interface{} {
+	output := make(chan map[string]interface{})
+
+	go func() {
+		defer close(output)
+
+		g := newGrouper[T](key, aggs)
+		for item := range input {
+			g.add(item)
+		}
+		for _, row := range g.rows() {
+			output <- row
+		}
+	}()
+
+	return output
+}
+
+// GroupBySeq groups the items of seq like GroupBy, only keeping a row per group in memory
+func GroupBySeq[T any](seq iter.Seq[T], key string, aggs ...Aggregator) []map[string]interface{} {
+	g := newGrouper[T](key, aggs)
+	for item := range seq {
+		g.add(item)
+	}
+	return g.rows()
+}
+
+// group is the state of a group: its value and an accumulator per aggregator
+type group struct {
+	value interface{}
+	accs  []accumulator
+}
+
+type grouper[T any] struct {
+	key    string
+	aggs   []Aggregator
+	groups map[interface{}]*group
+	order  []*group
+}
+
+func newGrouper[T any](key string, aggs []Aggregator) *grouper[T] {
+	return &grouper[T]{key: key, aggs: aggs, groups: map[interface{}]*group{}}
+}
+
+func (g *grouper[T]) add(item T) {
+	value := fieldOrItem(item, g.key)
+	id := groupID(value)
+
+	grp, ok := g.groups[id]
+	if !ok {
+		grp = &group{value: value, accs: make([]accumulator, len(g.aggs))}
+		for i, agg := range g.aggs {
+			grp.accs[i] = agg.new()
+		}
+		g.groups[id] = grp
+		g.order = append(g.order, grp)
+	}
+
+	for i, agg := range g.aggs {
+		grp.accs[i].add(fieldOrItem(item, agg.field))
+	}
+}
+
+func (g *grouper[T]) rows() []map[string]interface{} {
+	rows := make([]map[string]interface{}, len(g.order))
+	for i, grp := range g.order {
+		row := make(map[string]interface{}, len(g.aggs)+1)
+		row[g.key] = grp.value
+		for j, agg := range g.aggs {
+			row[agg.name] = grp.accs[j].result()
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// nanID is the group ID of NaN, which isn't equal to itself as a map key
+type nanID struct{}
+
+// formattedID is the group ID of a value that can't be a map key, so it can't collide with strings
+type formattedID string
+
+// groupID returns the map key of the group of value: numbers are keyed by their
+// float64 value, values that can't be map keys by their formatted value
+func groupID(value interface{}) interface{} {
+	if num, ok := toNumber(value); ok {
+		if math.IsNaN(num) {
+			return nanID{}
+		}
+		return num
+	}
+	// unlike the type, the value checks what interfaces in it hold, like a slice in an interface field
+	if value != nil && !reflect.ValueOf(value).Comparable() {
+		return formattedID(fmt.Sprintf("%T %v", value, value))
+	}
+	return value
+}
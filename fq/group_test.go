@@ -0,0 +1,116 @@
+package fq
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestGroupBy(t *testing.T) {
+	products := getManyTestProducts(10)
+
+	rows := GroupBy(products, "InStock", Count(), Sum("Stock"), Avg("Rating"), Min("Price"), Max("Name").As("last"))
+
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 groups, got %v", rows)
+	}
+
+	// products 1-4 are in stock, product 5 isn't, repeated twice
+	expected := []map[string]interface{}{
+		{"InStock": true, "count": 8, "sum_Stock": 2 * float64(45+120+15+200), "avg_Rating": (4.7 + 4.1 + 4.9 + 4.5) / 4, "min_Price": 159.99, "last": "Wireless Earbuds"},
+		{"InStock": false, "count": 2, "sum_Stock": float64(0), "avg_Rating": 3.0, "min_Price": 99.99, "last": "Out of Stock Item"},
+	}
+	for i, row := range rows {
+		for key, want := range expected[i] {
+			got := row[key]
+			if f, ok := want.(float64); ok && key == "avg_Rating" {
+				if g, ok := got.(float64); !ok || g-f > 1e-9 || f-g > 1e-9 {
+					t.Errorf("Group %d: expected %s %v, got %v", i, key, want, got)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Group %d: expected %s %v (%T), got %v (%T)", i, key, want, want, got, got)
+			}
+		}
+	}
+
+	t.Run("dynamic items", func(t *testing.T) {
+		items := []interface{}{
+			map[string]interface{}{"user": map[string]interface{}{"country": "FR"}, "n": 1, "tags": []interface{}{"a"}},
+			map[string]interface{}{"user": map[string]interface{}{"country": "US"}, "n": 1.0, "tags": []interface{}{"a"}},
+			map[string]interface{}{"user": map[string]interface{}{"country": "FR"}, "n": "x", "tags": []interface{}{"b"}},
+			map[string]interface{}{"n": nil},
+		}
+
+		byCountry := GroupBy(items, "user.country", Count(), Sum("n"), Avg("missing"), Min("n"))
+		if len(byCountry) != 3 {
+			t.Fatalf("Expected 3 groups, got %v", byCountry)
+		}
+		if byCountry[0]["user.country"] != "FR" || byCountry[0]["count"] != 2 || byCountry[0]["sum_n"] != 1.0 || byCountry[0]["min_n"] != 1 {
+			t.Errorf("Unexpected FR group %v", byCountry[0])
+		}
+		if byCountry[2]["user.country"] != nil || byCountry[2]["avg_missing"] != nil || byCountry[2]["min_n"] != nil {
+			t.Errorf("Unexpected group for missing field %v", byCountry[2])
+		}
+
+		byNumber := GroupBy(items, "n", Count())
+		if len(byNumber) != 3 || byNumber[0]["count"] != 2 {
+			t.Errorf("Expected 1 and 1.0 in the same group, got %v", byNumber)
+		}
+
+		byTags := GroupBy(items, "tags", Count())
+		if len(byTags) != 3 || byTags[0]["count"] != 2 {
+			t.Errorf("Expected slices to group by value, got %v", byTags)
+		}
+	})
+
+	t.Run("unhashable and NaN keys", func(t *testing.T) {
+		type Key struct {
+			Value interface{}
+		}
+		items := []map[string]interface{}{
+			{"key": Key{[]int{1}}, "n": math.NaN()},
+			{"key": Key{[]int{1}}, "n": float32(math.NaN())},
+			{"key": Key{"[1]"}, "n": 1},
+			{"key": "fq.Key {[1]}", "n": 1},
+		}
+
+		byKey := GroupBy(items, "key", Count())
+		if len(byKey) != 3 || byKey[0]["count"] != 2 {
+			t.Errorf("Expected a struct holding a slice to group by value, got %v", byKey)
+		}
+
+		byNumber := GroupBy(items, "n", Count())
+		if len(byNumber) != 2 || byNumber[0]["count"] != 2 {
+			t.Errorf("Expected NaN values in a single group, got %v", byNumber)
+		}
+	})
+}
+
+func TestGroupByStreams(t *testing.T) {
+	products := getManyTestProducts(100)
+	expected := GroupBy(products, "Manufacturer.Country", Count(), Max("Price"))
+
+	input := make(chan Product)
+	go func() {
+		defer close(input)
+		for _, product := range products {
+			input <- product
+		}
+	}()
+
+	var rows []map[string]interface{}
+	for row := range GroupByC(input, "Manufacturer.Country", Count(), Max("Price")) {
+		rows = append(rows, row)
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %v, got %v", expected, rows)
+	}
+
+	rows = GroupBySeq(slices.Values(products), "Manufacturer.Country", Count(), Max("Price"))
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %v, got %v", expected, rows)
+	}
+}
@@ -0,0 +1,273 @@
+package fq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WithArrayPath selects the array of the JSON array sources by a dot path of object keys
+// and array indexes, like .data.items or .pages.0.items. The top-level value by default.
+func WithArrayPath(path string) SourceOption {
+	return func(o *sourceOptions) {
+		o.arrayPath = path
+	}
+}
+
+// JSONArraySourceStream creates a channel of the elements of the JSON array read from r, or of
+// the WithArrayPath array, and a channel for errors. Elements are decoded one at a time, so
+// memory doesn't grow with the size of the array. Invalid JSON ends the stream with an error.
+// gzip and bzip2 data is decompressed. r isn't closed.
+func JSONArraySourceStream(r io.Reader, opts ...SourceOption) (<-chan interface{}, <-chan error) {
+	output := make(chan interface{}, 100)
+	errCh := make(chan error, 10)
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		for obj, err := range JSONArraySourceSeq(r, opts...) {
+			if err != nil {
+				errCh <- err
+				continue
+			}
+			output <- obj
+		}
+	}()
+
+	return output, errCh
+}
+
+// JSONArraySourceSeq iterates over the elements of the JSON array read from r like
+// JSONArraySourceStream, yielding the error along with a nil object
+func JSONArraySourceSeq(r io.Reader, opts ...SourceOption) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		o := newSourceOptions(opts)
+		reader, err := decompress(r, "")
+		if err == nil {
+			err = readJSONArray(reader, o, yield)
+		}
+		if err != nil {
+			yield(nil, fmt.Errorf("error reading JSON array: %w", err))
+		}
+	}
+}
+
+// JSONReaderSourceSeq iterates over the elements of the JSON array read from r when it starts
+// with [ or WithArrayPath is set, like JSONArraySourceSeq, and over its JSONL objects otherwise,
+// like JSONLReaderSourceSeq
+func JSONReaderSourceSeq(r io.Reader, opts ...SourceOption) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		readJSON(r, "", newSourceOptions(opts), yield)
+	}
+}
+
+// JSONFileSourceSeq iterates over the JSON array or the JSONL objects of a file like
+// JSONReaderSourceSeq. The file is opened when iteration starts and closed when it stops.
+func JSONFileSourceSeq(filePath string, opts ...SourceOption) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to open file: %w", err))
+			return
+		}
+		defer file.Close()
+
+		readJSON(file, filePath, newSourceOptions(opts), yield)
+	}
+}
+
+// readJSON yields the elements of the JSON array or the JSONL objects of r, the content of
+// the file name if any, until yield returns false, yielding the read error if any
+func readJSON(r io.Reader, name string, o *sourceOptions, yield func(interface{}, error) bool) {
+	reader, err := decompress(r, name)
+	if err != nil {
+		yield(nil, fmt.Errorf("error reading input: %w", err))
+		return
+	}
+
+	b, lines, _, err := firstByte(reader)
+	if o.arrayPath != "" || err == nil && b == '[' {
+		if err := readJSONArray(reader, o, yield); err != nil {
+			yield(nil, fmt.Errorf("error reading JSON array: %w", err))
+		}
+		return
+	}
+
+	if err := readLines(reader, lines, o, yield); err != nil {
+		yield(nil, fmt.Errorf("error reading input: %w", err))
+	}
+}
+
+// firstByte peeks the first byte of reader that isn't a space, err is io.EOF if there's none.
+// Spaces that don't fit in the buffer of reader are discarded, lines being the number of line
+// endings discarded and discarded the number of bytes.
+func firstByte(reader *bufio.Reader) (b byte, lines int, discarded int64, err error) {
+	for n := 1; ; n++ {
+		peeked, err := reader.Peek(n)
+		if err == bufio.ErrBufferFull {
+			// the buffer is full of spaces
+			lines += bytes.Count(peeked, []byte("\n"))
+			discarded += int64(len(peeked))
+			reader.Discard(len(peeked))
+			n = 0
+			continue
+		}
+		if err != nil {
+			return 0, lines, discarded, err
+		}
+		switch peeked[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return peeked[n-1], lines, discarded, nil
+	}
+}
+
+// readJSONArray yields the elements of the o.arrayPath array of reader until yield returns false
+func readJSONArray(reader *bufio.Reader, o *sourceOptions, yield func(interface{}, error) bool) error {
+	if _, _, _, err := firstByte(reader); err == io.EOF {
+		return nil
+	}
+
+	decoder := json.NewDecoder(reader)
+	if err := seekArray(decoder, o.arrayPath); err != nil {
+		return err
+	}
+
+	for index := 0; decoder.More(); index++ {
+		obj, tooLong, err := decodeElement(decoder, o)
+		if err != nil {
+			return fmt.Errorf("element %d at offset %d: %w", index, decoder.InputOffset(), err)
+		}
+		if tooLong {
+			if !yield(nil, fmt.Errorf("element %d: %w (max %d bytes)", index, ErrLineTooLong, o.maxLineSize)) {
+				return nil
+			}
+			continue
+		}
+		if !yield(obj, nil) {
+			return nil
+		}
+	}
+
+	// the closing ]
+	if _, err := decoder.Token(); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("offset %d: %w", decoder.InputOffset(), err)
+	}
+	if o.arrayPath == "" {
+		if _, err := decoder.Token(); err != io.EOF {
+			return fmt.Errorf("invalid data after the array")
+		}
+	}
+	return nil
+}
+
+// decodeElement decodes the next element of decoder, reporting the ones longer than
+// o.maxLineSize bytes as tooLong. Elements are read in full before their size is checked.
+func decodeElement(decoder *json.Decoder, o *sourceOptions) (obj interface{}, tooLong bool, err error) {
+	if o.maxLineSize <= 0 {
+		err = decoder.Decode(&obj)
+		return obj, false, err
+	}
+
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, false, err
+	}
+	if len(raw) > o.maxLineSize {
+		return nil, true, nil
+	}
+	err = json.Unmarshal(raw, &obj)
+	return obj, false, err
+}
+
+// seekArray reads the tokens of decoder up to the start of the array at path
+func seekArray(decoder *json.Decoder, path string) error {
+	var keys []string
+	if trimmed := strings.Trim(path, "."); trimmed != "" {
+		keys = strings.Split(trimmed, ".")
+	}
+
+	for i, key := range keys {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		found := false
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				name, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				if name == key {
+					found = true
+					break
+				}
+				if err := skipValue(decoder); err != nil {
+					return err
+				}
+			}
+
+		case json.Delim('['):
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 {
+				break
+			}
+			for ; index > 0 && decoder.More(); index-- {
+				if err := skipValue(decoder); err != nil {
+					return err
+				}
+			}
+			found = decoder.More()
+		}
+
+		if !found {
+			return fmt.Errorf("array path %s: .%s not found", path, strings.Join(keys[:i+1], "."))
+		}
+	}
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		if len(keys) == 0 {
+			return fmt.Errorf("not an array")
+		}
+		return fmt.Errorf("array path %s: not an array", path)
+	}
+	return nil
+}
+
+// skipValue reads the tokens of the next value of decoder
+func skipValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
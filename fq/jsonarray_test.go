@@ -0,0 +1,138 @@
+package fq
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONArraySource(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		path     string
+		expected []interface{}
+		errorMsg string
+	}{
+		{"top-level array", ` [{"id": 1}, {"id": 2}, 3, "four", null]`, "", []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 2.0}, 3.0, "four", nil}, ""},
+		{"empty array", `[]`, "", nil, ""},
+		{"empty input", "  \n", "", nil, ""},
+		{"nested path", `{"meta": {"items": [0]}, "data": {"total": 2, "items": [{"id": 1}, {"id": 2}]}}`, ".data.items", []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 2.0}}, ""},
+		{"array index", `{"pages": [{"items": [1]}, {"items": [2, 3]}]}`, "pages.1.items", []interface{}{2.0, 3.0}, ""},
+		{"missing key", `{"data": {"rows": []}}`, ".data.items", nil, "array path .data.items: .data.items not found"},
+		{"index out of range", `[[1], [2]]`, ".2", nil, "array path .2: .2 not found"},
+		{"not an array", `{"id": 1}`, "", nil, "not an array"},
+		{"path to an object", `{"data": {}}`, ".data", nil, "array path .data: not an array"},
+		{"invalid element", `[{"id": 1}, {"id": }]`, "", []interface{}{map[string]interface{}{"id": 1.0}}, "element 1 at offset"},
+		{"truncated", `[{"id": 1}`, "", []interface{}{map[string]interface{}{"id": 1.0}}, "unexpected end of JSON input"},
+		{"trailing data", `[1] [2]`, "", []interface{}{1.0}, "invalid data after the array"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []SourceOption
+			if tc.path != "" {
+				opts = append(opts, WithArrayPath(tc.path))
+			}
+
+			results, errs := collectResults(JSONArraySourceStream(strings.NewReader(tc.content), opts...))
+
+			if !reflect.DeepEqual(results, tc.expected) && (len(results) > 0 || len(tc.expected) > 0) {
+				t.Errorf("Expected %v, got %v", tc.expected, results)
+			}
+			if tc.errorMsg == "" && len(errs) > 0 || tc.errorMsg != "" && (len(errs) != 1 || !strings.Contains(errs[0].Error(), tc.errorMsg)) {
+				t.Errorf("Expected error %q, got %v", tc.errorMsg, errs)
+			}
+		})
+	}
+
+	t.Run("stops early", func(t *testing.T) {
+		var ids []interface{}
+		for obj, err := range JSONArraySourceSeq(strings.NewReader(`[1, 2, 3, invalid`)) {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids = append(ids, obj)
+			if len(ids) == 2 {
+				break
+			}
+		}
+		if !reflect.DeepEqual(ids, []interface{}{1.0, 2.0}) {
+			t.Errorf("Expected 1 and 2, got %v", ids)
+		}
+	})
+
+	t.Run("max element size", func(t *testing.T) {
+		content := `[{"id": 1}, {"id": 2, "padding": "` + strings.Repeat("x", 100) + `"}, {"id": 3}]`
+		results, errs := collectResults(JSONArraySourceStream(strings.NewReader(content), WithMaxLineSize(64)))
+
+		expected := []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 3.0}}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+		if len(errs) != 1 || !errors.Is(errs[0], ErrLineTooLong) || !strings.Contains(errs[0].Error(), "element 1") {
+			t.Errorf("Expected element 1 to be too long, got %v", errs)
+		}
+	})
+}
+
+func TestJSONFileSource(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"array.json":  "\n  [{\"id\": 1}, {\"id\": 2}]",
+		"lines.jsonl": "{\"id\": 1}\n{\"id\": 2}\n",
+		"nested.json": `{"data": {"items": [{"id": 1}, {"id": 2}]}}`,
+		// more spaces than the read buffer holds
+		"spaced.json": strings.Repeat(" \n", 5000) + `[{"id": 1}, {"id": 2}]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	expected := []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 2.0}}
+	for name, opts := range map[string][]SourceOption{
+		"array.json":  nil,
+		"lines.jsonl": nil,
+		"nested.json": {WithArrayPath("data.items")},
+		"spaced.json": nil,
+	} {
+		var results []interface{}
+		for obj, err := range JSONFileSourceSeq(filepath.Join(dir, name), opts...) {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			results = append(results, obj)
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, results)
+		}
+	}
+
+	var results []interface{}
+	for obj, err := range JSONReaderSourceSeq(strings.NewReader("[1]\n[2]\n")) {
+		if err == nil {
+			results = append(results, obj)
+		} else if !strings.Contains(err.Error(), "invalid data after the array") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(results, []interface{}{1.0}) {
+		t.Errorf("Expected JSONL of arrays to be read as an array, got %v", results)
+	}
+
+	// the blank lines discarded looking for an array still count
+	var errs []error
+	for _, err := range JSONReaderSourceSeq(strings.NewReader(strings.Repeat("\n", 5000) + "{\"id\": 1}\ninvalid\n")) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "line 5002: error parsing JSON") {
+		t.Errorf("Expected an error on line 5002, got %v", errs)
+	}
+}
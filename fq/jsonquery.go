@@ -0,0 +1,237 @@
+package fq
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ParseJSONQuery parses a Mongo-style query document into a Query:
+//
+//	{"price": {"$lt": 500}, "$or": [{"category": "books"}, {"tags": {"$all": ["sale"]}}]}
+//
+// Field keys (dot paths included) map to Q fields, nested documents without operators to
+// nested Q and other values to equality. Operators map to the fq operators:
+//
+//	$eq $ne $gt $gte $lt $lte        Eq, Not(Eq), Gt, Gte, Lt, Lte
+//	$in $nin                          In, Not(In)
+//	$all                              ContainsAll
+//	$regex (with $options "i")        Match with a *regexp.Regexp
+//	$exists                           Not(nil), nil (fields set to null don't exist)
+//	$not                              Not
+//	$and $or $nor                     And, Or, Not(Or)
+//	$contains $match $hasItem $containsAny
+//	$geoWithin [lat, lng, radiusKm]   GeoWithin
+//
+// Operators apply to the value they're nested in, so $and, $or and $nor can be used both
+// at the document level and on a field. Numbers are decoded as float64, like JSON data.
+// Unknown operators are reported as *ErrUnknownOperator and invalid operands as *ErrInvalidOperand.
+func ParseJSONQuery(data []byte) (Query, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON query: %w", err)
+	}
+
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, &ErrInvalidOperand{Op: "query", Operand: doc, Reason: "not a JSON object"}
+	}
+
+	query, err := parseQueryValue(doc, "")
+	if query == nil && err == nil {
+		// {"$exists": false}, a nil Query would match everything
+		return Q{"": nil}, nil
+	}
+	return query, err
+}
+
+// parseQueryValue converts a decoded JSON value into the Query it stands for at path
+func parseQueryValue(value interface{}, path string) (Query, error) {
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		// scalars, arrays and null match equal values
+		return value, nil
+	}
+
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	query := Q{}
+	var ops []Query
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "$") {
+			field, err := parseQueryValue(doc[key], joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			query[key] = field
+			continue
+		}
+
+		if key == "$options" {
+			if _, ok := doc["$regex"]; !ok {
+				return nil, &ErrInvalidOperand{Path: path, Op: key, Operand: doc[key], Reason: "$options without $regex"}
+			}
+			continue
+		}
+
+		op, err := parseJSONOperator(key, doc[key], doc, path)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+
+	var op Query
+	switch len(ops) {
+	case 0:
+		return query, nil
+	case 1:
+		op = ops[0]
+	default:
+		op = And(ops...)
+	}
+
+	if len(query) == 0 {
+		return op, nil
+	}
+	query[""] = op
+	return query, nil
+}
+
+// parseJSONOperator converts the operator name with its operand arg, doc is the document
+// holding the operator (for $regex options)
+func parseJSONOperator(name string, arg interface{}, doc map[string]interface{}, path string) (Query, error) {
+	invalid := func(reason string) error {
+		return &ErrInvalidOperand{Path: path, Op: name, Operand: arg, Reason: reason}
+	}
+
+	switch name {
+	case "$eq":
+		return Eq(arg), nil
+	case "$ne":
+		return Not(Eq(arg)), nil
+	case "$gt":
+		return Gt(arg), nil
+	case "$gte":
+		return Gte(arg), nil
+	case "$lt":
+		return Lt(arg), nil
+	case "$lte":
+		return Lte(arg), nil
+
+	case "$in", "$nin", "$all", "$containsAny":
+		items, ok := arg.([]interface{})
+		if !ok {
+			return nil, invalid("not an array")
+		}
+		switch name {
+		case "$in":
+			return In(items...), nil
+		case "$nin":
+			return Not(In(items...)), nil
+		case "$all":
+			return ContainsAll(items...), nil
+		default:
+			return ContainsAny(items...), nil
+		}
+
+	case "$hasItem":
+		return HasItem(arg), nil
+
+	case "$contains", "$match":
+		s, ok := arg.(string)
+		if !ok {
+			return nil, invalid("not a string")
+		}
+		if name == "$contains" {
+			return Contains(s), nil
+		}
+		return Match(s), nil
+
+	case "$regex":
+		pattern, ok := arg.(string)
+		if !ok {
+			return nil, invalid("not a string")
+		}
+		if options, ok := doc["$options"]; ok {
+			switch options {
+			case "":
+			case "i":
+				pattern = "(?i)" + pattern
+			default:
+				return nil, &ErrInvalidOperand{Path: path, Op: "$options", Operand: options, Reason: `only "i" is supported`}
+			}
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, invalid(err.Error())
+		}
+		return Match(re), nil
+
+	case "$exists":
+		exists, ok := arg.(bool)
+		if !ok {
+			return nil, invalid("not a boolean")
+		}
+		if exists {
+			return Not(nil), nil
+		}
+		return nil, nil
+
+	case "$geoWithin":
+		coords, ok := arg.([]interface{})
+		if !ok || len(coords) != 3 {
+			return nil, invalid("not an array of [lat, lng, radiusKm]")
+		}
+		var nums [3]float64
+		for i, coord := range coords {
+			n, ok := coord.(float64)
+			if !ok {
+				return nil, invalid("not an array of [lat, lng, radiusKm]")
+			}
+			nums[i] = n
+		}
+		return GeoWithin(nums[0], nums[1], nums[2]), nil
+
+	case "$not":
+		if _, ok := arg.(map[string]interface{}); !ok {
+			return nil, invalid("not a query document")
+		}
+		child, err := parseQueryValue(arg, path)
+		if err != nil {
+			return nil, err
+		}
+		return Not(child), nil
+
+	case "$and", "$or", "$nor":
+		items, ok := arg.([]interface{})
+		if !ok || len(items) == 0 {
+			return nil, invalid("not a non-empty array")
+		}
+		children := make([]Query, len(items))
+		for i, item := range items {
+			child, err := parseQueryValue(item, path)
+			if err != nil {
+				return nil, err
+			}
+			children[i] = child
+		}
+		switch name {
+		case "$and":
+			return And(children...), nil
+		case "$or":
+			return Or(children...), nil
+		default:
+			return Not(Or(children...)), nil
+		}
+
+	default:
+		return nil, &ErrUnknownOperator{Path: path, Op: name}
+	}
+}
@@ -0,0 +1,132 @@
+package fq
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONQuery(t *testing.T) {
+	var records []interface{}
+	for _, line := range []string{
+		`{"id": 1, "name": "Laptop", "price": 999, "tags": ["work", "sale"], "user": {"email": "ana@example.com"}, "loc": [48.85, 2.35]}`,
+		`{"id": 2, "name": "book", "price": 20, "tags": ["read"], "user": {"email": "bob@example.org"}, "loc": [40.71, -74.0]}`,
+		`{"id": 3, "name": "Lamp", "price": 45, "tags": ["sale"], "user": null}`,
+		`{"id": 4, "name": "desk", "price": 300, "tags": [], "discount": 10}`,
+	} {
+		var record interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid test record: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []float64
+	}{
+		{"empty", `{}`, []float64{1, 2, 3, 4}},
+		{"equality", `{"name": "book"}`, []float64{2}},
+		{"comparison", `{"price": {"$lt": 500}}`, []float64{2, 3, 4}},
+		{"range", `{"price": {"$gte": 20, "$lte": 300}}`, []float64{2, 3, 4}},
+		{"ne", `{"name": {"$ne": "book"}}`, []float64{1, 3, 4}},
+		{"in", `{"id": {"$in": [1, 3]}}`, []float64{1, 3}},
+		{"nin", `{"id": {"$nin": [1, 3]}}`, []float64{2, 4}},
+		{"all", `{"tags": {"$all": ["work", "sale"]}}`, []float64{1}},
+		{"hasItem", `{"tags": {"$hasItem": "sale"}}`, []float64{1, 3}},
+		{"containsAny", `{"tags": {"$containsAny": ["read", "work"]}}`, []float64{1, 2}},
+		{"regex", `{"name": {"$regex": "^l", "$options": "i"}}`, []float64{1, 3}},
+		{"case sensitive regex", `{"name": {"$regex": "^L"}}`, []float64{1, 3}},
+		{"contains", `{"user.email": {"$contains": ".org"}}`, []float64{2}},
+		{"match", `{"name": {"$match": "LAMP"}}`, []float64{3}},
+		{"exists", `{"discount": {"$exists": true}}`, []float64{4}},
+		{"not exists", `{"user": {"$exists": false}}`, []float64{3, 4}},
+		{"nested document", `{"user": {"email": "ana@example.com"}}`, []float64{1}},
+		{"geoWithin", `{"loc": {"$geoWithin": [48.86, 2.34, 5]}}`, []float64{1}},
+		{"not", `{"price": {"$not": {"$gt": 100}}}`, []float64{2, 3}},
+		{"or", `{"$or": [{"price": {"$lt": 30}}, {"tags": {"$hasItem": "work"}}]}`, []float64{1, 2}},
+		{"nor", `{"$nor": [{"price": {"$lt": 30}}, {"tags": {"$hasItem": "work"}}]}`, []float64{3, 4}},
+		{"and with fields", `{"tags": {"$hasItem": "sale"}, "$and": [{"price": {"$lt": 100}}]}`, []float64{3}},
+		{"field level or", `{"price": {"$or": [20, {"$gt": 900}]}}`, []float64{1, 2}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := ParseJSONQuery([]byte(tc.query))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result, err := Filter(records, query, 0, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var ids []float64
+			for _, record := range result {
+				ids = append(ids, record.(map[string]interface{})["id"].(float64))
+			}
+			if len(ids) != len(tc.expected) {
+				t.Fatalf("Expected ids %v, got %v", tc.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tc.expected[i] {
+					t.Fatalf("Expected ids %v, got %v", tc.expected, ids)
+				}
+			}
+		})
+	}
+
+	t.Run("structs", func(t *testing.T) {
+		query, err := ParseJSONQuery([]byte(`{"Price": {"$lt": 500}, "InStock": true, "Address.Country": {"$in": ["USA"]}}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		result, err := Filter(getTestProducts(), query, 0, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ids := productIDs(result); !equalInts(ids, []int{4}) {
+			t.Errorf("Expected [4], got %v", ids)
+		}
+	})
+}
+
+func TestParseJSONQueryErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		errorSubstring string
+	}{
+		{"invalid JSON", `{"price": `, "invalid JSON query"},
+		{"not an object", `[1, 2]`, "not a JSON object"},
+		{"unknown operator", `{"price": {"$between": [1, 2]}}`, `field "price": unknown operator $between`},
+		{"in operand", `{"id": {"$in": 1}}`, "$in: invalid operand 1: not an array"},
+		{"regex", `{"name": {"$regex": "("}}`, "missing closing )"},
+		{"regex options", `{"name": {"$regex": "a", "$options": "x"}}`, `only "i" is supported`},
+		{"options without regex", `{"name": {"$options": "i"}}`, "$options without $regex"},
+		{"or operand", `{"$or": {"a": 1}}`, "not a non-empty array"},
+		{"nested error", `{"$or": [{"user.email": {"$foo": 1}}]}`, `field "user.email": unknown operator $foo`},
+		{"geoWithin", `{"loc": {"$geoWithin": [1, 2]}}`, "[lat, lng, radiusKm]"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseJSONQuery([]byte(tc.query))
+			if err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+			if !strings.Contains(err.Error(), tc.errorSubstring) {
+				t.Errorf("Expected error containing %q, got: %v", tc.errorSubstring, err)
+			}
+		})
+	}
+
+	_, err := ParseJSONQuery([]byte(`{"a": {"$foo": 1}}`))
+	var unknown *ErrUnknownOperator
+	if !errors.As(err, &unknown) || unknown.Path != "a" || unknown.Op != "$foo" {
+		t.Errorf("Expected an *ErrUnknownOperator for a.$foo, got %v", err)
+	}
+}
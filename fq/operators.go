@@ -5,68 +5,136 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 )
 
+// operator describes a query built by one of the operator functions below,
+// so the otherwise opaque P can be inspected (see describe)
+type operator struct {
+	name     string
+	args     []interface{}
+	children []Query
+	strict   bool // comparison reporting type mismatches (see Strict)
+}
+
+// describeProbe is passed to an operator P to retrieve its description
+type describeProbe struct {
+	op *operator
+}
+
+// newOperator wraps fn into a P that can be described (see describe)
+func newOperator(name string, args []interface{}, children []Query, fn P) P {
+	return wrapOperator(&operator{name: name, args: args, children: children}, fn)
+}
+
+// wrapOperator wraps fn into a P that answers a describeProbe with op, registering
+// its code in operatorCodes
+func wrapOperator(op *operator, fn P) P {
+	p := P(func(v interface{}) bool {
+		if probe, ok := v.(*describeProbe); ok {
+			probe.op = op
+			return false
+		}
+		return fn(v)
+	})
+
+	code := reflect.ValueOf(p).Pointer()
+	if _, ok := operatorCodes.Load(code); !ok {
+		operatorCodes.Store(code, true)
+	}
+	return p
+}
+
+// operatorCodes is the set of the code pointers of the Ps built by wrapOperator, which is how
+// describe tells them apart from custom predicates without calling those. Wherever the compiler
+// puts a copy of the closure code, the copy is registered the first time it builds a P.
+var operatorCodes sync.Map
+
+// describe returns the description of a query built by an operator function,
+// or nil for custom predicates and any other query type
+func describe(query Query) *operator {
+	var p P
+	switch q := query.(type) {
+	case P:
+		p = q
+	case func(interface{}) bool:
+		p = q
+	default:
+		return nil
+	}
+
+	if p == nil {
+		return nil
+	}
+	if _, ok := operatorCodes.Load(reflect.ValueOf(p).Pointer()); !ok {
+		return nil
+	}
+
+	probe := &describeProbe{}
+	p(probe)
+	return probe.op
+}
+
 // Eq checks for equality
 func Eq(val interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("eq", []interface{}{val}, nil, func(v interface{}) bool {
 		return isEqual(v, val)
-	}
+	})
 }
 
 // Gt checks if a value is greater than threshold
 func Gt(threshold interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("gt", []interface{}{threshold}, nil, func(v interface{}) bool {
 		return compareValues(v, threshold) > 0
-	}
+	})
 }
 
 // Lt checks if a value is less than threshold
 func Lt(threshold interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("lt", []interface{}{threshold}, nil, func(v interface{}) bool {
 		return compareValues(v, threshold) < 0
-	}
+	})
 }
 
 // Gte checks if a value is greater than or equal to threshold
 func Gte(threshold interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("gte", []interface{}{threshold}, nil, func(v interface{}) bool {
 		return compareValues(v, threshold) >= 0
-	}
+	})
 }
 
 // Lte checks if a value is less than or equal to threshold
 func Lte(threshold interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("lte", []interface{}{threshold}, nil, func(v interface{}) bool {
 		return compareValues(v, threshold) <= 0
-	}
+	})
 }
 
 // In checks if value matches any provided values
 func In(vals ...interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("in", vals, nil, func(v interface{}) bool {
 		for _, val := range vals {
 			if reflect.DeepEqual(v, val) {
 				return true
 			}
 		}
 		return false
-	}
+	})
 }
 
 // Contains checks if a string contains substring
 func Contains(substr string) P {
-	return func(v interface{}) bool {
+	return newOperator("contains", []interface{}{substr}, nil, func(v interface{}) bool {
 		if s, ok := v.(string); ok {
 			return strings.Contains(s, substr)
 		}
 		return false
-	}
+	})
 }
 
 // HasItem checks if an array contains the item
 func HasItem(item interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("hasitem", []interface{}{item}, nil, func(v interface{}) bool {
 		switch arr := v.(type) {
 		case []interface{}:
 			for _, val := range arr {
@@ -97,12 +165,12 @@ func HasItem(item interface{}) P {
 			}
 		}
 		return false
-	}
+	})
 }
 
 // GeoWithin checks if a location is within a given radius of a center point using the Haversine formula
 func GeoWithin(centerLat, centerLng, radiusKm float64) P {
-	return func(v interface{}) bool {
+	return newOperator("geowithin", []interface{}{centerLat, centerLng, radiusKm}, nil, func(v interface{}) bool {
 		var lat, lng float64
 		var ok bool
 
@@ -123,7 +191,7 @@ func GeoWithin(centerLat, centerLng, radiusKm float64) P {
 				}
 			}
 		default:
-				rv := reflect.ValueOf(v)
+			rv := reflect.ValueOf(v)
 			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
 				return false
 			}
@@ -160,12 +228,12 @@ func GeoWithin(centerLat, centerLng, radiusKm float64) P {
 		distance := R * c
 
 		return distance <= radiusKm
-	}
+	})
 }
 
 // Match checks if a string matches a pattern (string contains or regex)
 func Match(pattern interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("match", []interface{}{pattern}, nil, func(v interface{}) bool {
 		str := ""
 		switch sv := v.(type) {
 		case string:
@@ -186,12 +254,12 @@ func Match(pattern interface{}) P {
 			patternStr := strings.ToLower(strings.TrimSpace(reflect.ValueOf(pattern).String()))
 			return strings.Contains(str, patternStr)
 		}
-	}
+	})
 }
 
 // ContainsAll checks if an array contains all specified items
 func ContainsAll(items ...interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("containsall", items, nil, func(v interface{}) bool {
 		arr := reflect.ValueOf(v)
 		if arr.Kind() != reflect.Slice && arr.Kind() != reflect.Array {
 			return false
@@ -211,12 +279,12 @@ func ContainsAll(items ...interface{}) P {
 		}
 
 		return true
-	}
+	})
 }
 
 // ContainsAny checks if an array contains any of the specified items
 func ContainsAny(items ...interface{}) P {
-	return func(v interface{}) bool {
+	return newOperator("containsany", items, nil, func(v interface{}) bool {
 		switch arr := v.(type) {
 		case []interface{}:
 			for _, item := range items {
@@ -252,36 +320,36 @@ func ContainsAny(items ...interface{}) P {
 			}
 		}
 		return false
-	}
+	})
 }
 
 // Or combines values with logical OR
 func Or(vals ...Query) P {
-	return func(v interface{}) bool {
+	return newOperator("or", nil, vals, func(v interface{}) bool {
 		for _, val := range vals {
 			if eval(val, v) {
 				return true
 			}
 		}
 		return false
-	}
+	})
 }
 
 // And combines predicates with logical AND
 func And(predicates ...Query) P {
-	return func(v interface{}) bool {
+	return newOperator("and", nil, predicates, func(v interface{}) bool {
 		for _, p := range predicates {
 			if !eval(p, v) {
 				return false
 			}
 		}
 		return true
-	}
+	})
 }
 
 // Not negates a predicate
 func Not(p Query) P {
-	return func(v interface{}) bool {
+	return newOperator("not", nil, []Query{p}, func(v interface{}) bool {
 		return !eval(p, v)
-	}
+	})
 }
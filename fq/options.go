@@ -0,0 +1,143 @@
+package fq
+
+import (
+	"time"
+)
+
+// Option configures a Filter or FilterC call
+type Option func(*options)
+
+type options struct {
+	hooks    Hooks
+	tagNames bool
+}
+
+// Hooks are callbacks invoked while filtering, all of them are optional.
+// FilterC calls them from its filtering goroutine, so they must not block.
+// FilterCParallel calls them from its workers too, one call at a time.
+type Hooks struct {
+	// OnItemEvaluated is called after each evaluation with its outcome and duration
+	OnItemEvaluated func(item interface{}, matched bool, duration time.Duration)
+	// OnMatch is called for each matching item, including the ones dropped by skip
+	OnMatch func(item interface{})
+	// OnSkip is called for each matching item dropped by skip
+	OnSkip func(item interface{})
+	// OnPanic is called when evaluating item panics, with the recovered value
+	OnPanic func(item interface{}, recovered interface{})
+	// OnLimitReached is called once the limit of results is reached
+	OnLimitReached func(limit int)
+	// OnComplete is called once filtering ends, for any reason
+	OnComplete func(stats Stats)
+}
+
+// Stats summarizes a Filter or FilterC call
+type Stats struct {
+	Evaluated int           // items evaluated
+	Matched   int           // items that matched, including skipped ones
+	Skipped   int           // matching items dropped by skip
+	Returned  int           // items returned or sent
+	Panics    int           // evaluations that panicked
+	EvalTime  time.Duration // total time spent evaluating items (only measured with OnItemEvaluated or OnComplete)
+	Duration  time.Duration // total duration of the call
+	Err       error         // error the call ended with, if any
+}
+
+// WithHooks sets the hooks invoked while filtering
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// WithTagNames lets query keys name struct fields by tag: a key that isn't the Go name of a field
+// is looked up in the `fq:"..."` tags, then in the `json:"..."` tags. Struct fields are only
+// resolved by their Go names by default. It also applies to Compile, Validate and Explain.
+func WithTagNames() Option {
+	return func(o *options) {
+		o.tagNames = true
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// observer tracks the Stats of a filtering call and invokes its hooks
+type observer[T any] struct {
+	hooks Hooks
+	stats Stats
+	start time.Time
+	timed bool
+}
+
+func newObserver[T any](o *options) *observer[T] {
+	return &observer[T]{
+		hooks: o.hooks,
+		start: time.Now(),
+		timed: o.hooks.OnItemEvaluated != nil || o.hooks.OnComplete != nil,
+	}
+}
+
+// begin counts an evaluation and returns its start time, if evaluations are timed.
+// Counting evaluations as they start counts items whose evaluation panics once.
+func (o *observer[T]) begin() time.Time {
+	o.stats.Evaluated++
+	if o.timed {
+		return time.Now()
+	}
+	return time.Time{}
+}
+
+// evaluated records the outcome of an evaluation started at start.
+// Items are passed by pointer to avoid copies when no hook needs them.
+func (o *observer[T]) evaluated(item *T, matched bool, start time.Time) {
+	if o.timed {
+		duration := time.Since(start)
+		o.stats.EvalTime += duration
+		if o.hooks.OnItemEvaluated != nil {
+			o.hooks.OnItemEvaluated(*item, matched, duration)
+		}
+	}
+	if matched {
+		o.stats.Matched++
+		if o.hooks.OnMatch != nil {
+			o.hooks.OnMatch(*item)
+		}
+	}
+}
+
+func (o *observer[T]) panicked(item *T, recovered interface{}) {
+	o.stats.Panics++
+	if o.hooks.OnPanic != nil {
+		o.hooks.OnPanic(*item, recovered)
+	}
+}
+
+func (o *observer[T]) skipped(item *T) {
+	o.stats.Skipped++
+	if o.hooks.OnSkip != nil {
+		o.hooks.OnSkip(*item)
+	}
+}
+
+func (o *observer[T]) returned() {
+	o.stats.Returned++
+}
+
+func (o *observer[T]) limitReached(limit int) {
+	if o.hooks.OnLimitReached != nil {
+		o.hooks.OnLimitReached(limit)
+	}
+}
+
+func (o *observer[T]) complete(err error) {
+	if o.hooks.OnComplete != nil {
+		o.stats.Duration = time.Since(o.start)
+		o.stats.Err = err
+		o.hooks.OnComplete(o.stats)
+	}
+}
@@ -0,0 +1,221 @@
+package fq
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// FilterCParallel filters data like FilterC, evaluating items on workers goroutines
+// (runtime.GOMAXPROCS(0) when workers <= 0).
+// When ordered is true results are sent in input order, otherwise as soon as they're evaluated.
+// skip and limit apply to results in the order they're sent.
+func FilterCParallel[T any](input <-chan T, query Query, workers int, ordered bool, skip int, limit int, opts ...Option) (<-chan T, <-chan error) {
+	return FilterCParallelCtx(context.Background(), input, query, workers, ordered, skip, limit, opts...)
+}
+
+// FilterCParallelCtx filters data like FilterCParallel but stops as soon as ctx is done (see FilterCCtx)
+func FilterCParallelCtx[T any](ctx context.Context, input <-chan T, query Query, workers int, ordered bool, skip int, limit int, opts ...Option) (<-chan T, <-chan error) {
+	o := newOptions(opts)
+	obs := newObserver[T](o)
+	match, err := compileMatch[T](query, o)
+	if err != nil {
+		obs.complete(err)
+		return failedC[T](err)
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	return filterParallel(ctx, input, match, workers, ordered, skip, limit, obs)
+}
+
+// outcome is the evaluation result of the item at position seq of the input
+type outcome[T any] struct {
+	seq     int
+	item    T
+	matches bool
+	err     error
+}
+
+// filterParallel streams the items of input for which match is true, calling match from
+// several worker goroutines. A single collector goroutine applies skip and limit, and
+// completes obs once it's done and the workers are stopped.
+func filterParallel[T any](ctx context.Context, input <-chan T, match func(T) bool, workers int, ordered bool, skip int, limit int, obs *observer[T]) (<-chan T, <-chan error) {
+	output := make(chan T)
+	errCh := make(chan error, 1)
+
+	// stop ends the dispatcher and the workers once the collector is done
+	inner, stop := context.WithCancel(ctx)
+
+	// window bounds the items in flight, including the ones the collector holds back
+	// until the items before them are evaluated, so a slow item can't pile up results
+	window := make(chan struct{}, 2*workers)
+	jobs := make(chan outcome[T])
+	outcomes := make(chan outcome[T], workers)
+
+	// mu serializes the observer, and so the hooks, between the workers and the collector
+	var mu sync.Mutex
+	// observe calls f with mu held, a hook panicking included
+	observe := func(f func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		f()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		for seq := 0; ; seq++ {
+			select {
+			case <-inner.Done():
+				return
+			case window <- struct{}{}:
+			}
+
+			select {
+			case <-inner.Done():
+				return
+			case item, ok := <-input:
+				if !ok {
+					return
+				}
+				select {
+				case jobs <- outcome[T]{seq: seq, item: item}:
+				case <-inner.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				job := job
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							observe(func() { obs.panicked(&job.item, r) })
+							job.matches = false
+							job.err = recoveredError(r, "panic during filter evaluation")
+						}
+					}()
+					var start time.Time
+					observe(func() { start = obs.begin() })
+					job.matches = match(job.item)
+					observe(func() { obs.evaluated(&job.item, job.matches, start) })
+				}()
+
+				select {
+				case outcomes <- job:
+				case <-inner.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	go func() {
+		var err error
+
+		defer close(output)
+		defer close(errCh)
+		defer func() {
+			stop()
+			// the workers still evaluating call the hooks, which must all run before OnComplete
+			for range outcomes {
+			}
+			observe(func() { obs.complete(err) })
+		}()
+
+		matched := 0
+		sent := 0
+
+		// emit handles the outcome of an item in output order, returning false once done
+		emit := func(res outcome[T]) bool {
+			if res.err != nil {
+				select {
+				case errCh <- res.err:
+				case <-ctx.Done():
+					err = ctx.Err()
+					reportCtxErr(ctx, errCh)
+					return false
+				}
+				return true
+			}
+
+			if !res.matches {
+				return true
+			}
+
+			matched++
+			if matched <= skip {
+				observe(func() { obs.skipped(&res.item) })
+				return true
+			}
+
+			select {
+			case output <- res.item:
+			case <-ctx.Done():
+				err = ctx.Err()
+				reportCtxErr(ctx, errCh)
+				return false
+			}
+			sent++
+			observe(obs.returned)
+
+			if limit > 0 && sent >= limit {
+				observe(func() { obs.limitReached(limit) })
+				return false
+			}
+			return true
+		}
+
+		pending := map[int]outcome[T]{}
+		next := 0
+
+		for res := range outcomes {
+			if !ordered {
+				<-window
+				if !emit(res) {
+					return
+				}
+				continue
+			}
+
+			pending[res.seq] = res
+			for {
+				res, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				<-window
+				if !emit(res) {
+					return
+				}
+			}
+		}
+
+		// the input ended or ctx was canceled, which also stops the dispatcher
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			reportCtxErr(ctx, errCh)
+		}
+	}()
+
+	return output, errCh
+}
@@ -0,0 +1,267 @@
+package fq
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowEven matches even numbers, sleeping a little so workers finish out of order
+var slowEven = P(func(v interface{}) bool {
+	time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+	return v.(int)%2 == 0
+})
+
+func intsC(n int) <-chan int {
+	input := make(chan int)
+	go func() {
+		defer close(input)
+		for i := 0; i < n; i++ {
+			input <- i
+		}
+	}()
+	return input
+}
+
+func TestFilterCParallel(t *testing.T) {
+	tests := []struct {
+		name     string
+		skip     int
+		limit    int
+		expected int
+	}{
+		{"all", 0, 0, 250},
+		{"skip", 10, 0, 240},
+		{"limit", 0, 20, 20},
+		{"skip and limit", 100, 50, 50},
+		{"skip past results", 300, 0, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name+"/ordered", func(t *testing.T) {
+			var result []int
+			dataCh, errCh := FilterCParallel(intsC(500), slowEven, 8, true, tc.skip, tc.limit)
+			for v := range dataCh {
+				result = append(result, v)
+			}
+			for err := range errCh {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if len(result) != tc.expected {
+				t.Fatalf("Expected %d results, got %d", tc.expected, len(result))
+			}
+			for i, v := range result {
+				if v != 2*(tc.skip+i) {
+					t.Fatalf("Expected %d at %d, got %d", 2*(tc.skip+i), i, v)
+				}
+			}
+		})
+
+		t.Run(tc.name+"/unordered", func(t *testing.T) {
+			var result []int
+			dataCh, errCh := FilterCParallel(intsC(500), slowEven, 8, false, tc.skip, tc.limit)
+			for v := range dataCh {
+				result = append(result, v)
+			}
+			for err := range errCh {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if len(result) != tc.expected {
+				t.Fatalf("Expected %d results, got %d", tc.expected, len(result))
+			}
+			seen := map[int]bool{}
+			for _, v := range result {
+				if v%2 != 0 || seen[v] {
+					t.Fatalf("Expected distinct even numbers, got %v", result)
+				}
+				seen[v] = true
+			}
+		})
+	}
+
+	t.Run("same results as FilterC", func(t *testing.T) {
+		products := getManyTestProducts(1000)
+		expected, err := Filter(products, benchmarkQuery, 0, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		input := make(chan Product)
+		go func() {
+			defer close(input)
+			for _, product := range products {
+				input <- product
+			}
+		}()
+
+		var result []Product
+		dataCh, errCh := FilterCParallel(input, benchmarkQuery, 0, true, 0, 0)
+		for product := range dataCh {
+			result = append(result, product)
+		}
+		for err := range errCh {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if len(result) != len(expected) {
+			t.Fatalf("Expected %d products, got %d", len(expected), len(result))
+		}
+		for i := range result {
+			if result[i].ID != expected[i].ID {
+				t.Fatalf("Expected product %d at %d, got %d", expected[i].ID, i, result[i].ID)
+			}
+		}
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		query := P(func(v interface{}) bool {
+			if v.(int)%10 == 0 {
+				panic("boom")
+			}
+			return true
+		})
+
+		var result []int
+		dataCh, errCh := FilterCParallel(intsC(100), query, 4, true, 0, 0)
+		var errs []error
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for err := range errCh {
+				errs = append(errs, err)
+			}
+		}()
+		for v := range dataCh {
+			result = append(result, v)
+		}
+		wg.Wait()
+
+		if len(result) != 90 || len(errs) != 10 {
+			t.Errorf("Expected 90 results and 10 errors, got %d and %d", len(result), len(errs))
+		}
+		if !sort.IntsAreSorted(result) {
+			t.Error("Expected results in input order")
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		dataCh, errCh := FilterCParallel(make(chan Product), Q{"Nme": "x"}, 4, true, 0, 0)
+		for range dataCh {
+			t.Error("Expected no results")
+		}
+		var unknown *ErrUnknownField
+		if err := <-errCh; !errors.As(err, &unknown) {
+			t.Errorf("Expected ErrUnknownField, got %v", err)
+		}
+	})
+
+	t.Run("limit stops the input", func(t *testing.T) {
+		input := make(chan int) // stays open
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for i := 0; ; i++ {
+				select {
+				case input <- i:
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		dataCh, _ := FilterCParallel(input, Gte(0), 4, true, 0, 3)
+		select {
+		case <-drained(dataCh):
+		case <-time.After(time.Second):
+			t.Fatal("Output channel was not closed after reaching the limit")
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		input := make(chan int)
+		go func() {
+			for i := 0; ; i++ {
+				select {
+				case input <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		dataCh, errCh := FilterCParallelCtx(ctx, input, Gte(0), 4, false, 0, 0)
+		<-dataCh
+
+		// stop reading from both channels, the goroutines must still exit
+		cancel()
+
+		select {
+		case <-drained(dataCh):
+		case <-time.After(time.Second):
+			t.Fatal("Output channel was not closed after cancel")
+		}
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("hooks", func(t *testing.T) {
+		var stats Stats
+		matches := 0
+		hooks := Hooks{
+			OnMatch:    func(item interface{}) { matches++ },
+			OnComplete: func(s Stats) { stats = s },
+		}
+
+		dataCh, errCh := FilterCParallel(intsC(100), slowEven, 8, false, 5, 0, WithHooks(hooks))
+		for range dataCh {
+		}
+		for range errCh {
+		}
+
+		if matches != 50 || stats.Evaluated != 100 || stats.Matched != 50 || stats.Skipped != 5 || stats.Returned != 45 {
+			t.Errorf("Unexpected stats %+v with %d matches", stats, matches)
+		}
+	})
+
+	t.Run("no hooks after OnComplete", func(t *testing.T) {
+		// hooks are called one at a time, so they need no locking
+		var stats Stats
+		evaluated, late := 0, 0
+		completed := false
+		hook := func() {
+			if completed {
+				late++
+			}
+		}
+		hooks := Hooks{
+			OnItemEvaluated: func(interface{}, bool, time.Duration) { evaluated++; hook() },
+			OnMatch:         func(interface{}) { hook() },
+			OnComplete:      func(s Stats) { stats, completed = s, true },
+		}
+
+		for _, ordered := range []bool{true, false} {
+			stats, evaluated, late, completed = Stats{}, 0, 0, false
+			dataCh, errCh := FilterCParallel(intsC(500), slowEven, 8, ordered, 0, 5, WithHooks(hooks))
+			for range dataCh {
+			}
+			for range errCh {
+			}
+
+			if !completed || late != 0 {
+				t.Errorf("ordered %v: expected no hook after OnComplete, got %d", ordered, late)
+			}
+			if stats.Evaluated != evaluated || stats.Returned != 5 {
+				t.Errorf("ordered %v: expected %d evaluated and 5 returned, got %+v", ordered, evaluated, stats)
+			}
+		}
+	})
+}
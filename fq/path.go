@@ -0,0 +1,123 @@
+package fq
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// splitPath splits a field path like "Profile.Location.Country" or "items.0.sku" into its segments.
+// A backslash escapes the next character, so `meta\.version` addresses the single key "meta.version".
+func splitPath(path string) []string {
+	var segments []string
+	var current strings.Builder
+	escaped := false
+
+	for _, char := range path {
+		switch {
+		case escaped:
+			current.WriteRune(char)
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case char == '.':
+			segments = append(segments, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(char)
+		}
+	}
+
+	return append(segments, current.String())
+}
+
+// resolvePath resolves a field name or dot path against v. A map key containing the rest of
+// an unescaped path, like "app.version", is looked up before the path is split at its dots,
+// so keys with literal dots match like they did before dot paths.
+func resolvePath(v reflect.Value, path string, tagNames bool) reflect.Value {
+	if strings.IndexAny(path, `.\`) < 0 {
+		return fieldValue(v, path, tagNames)
+	}
+	return resolveSegments(v, splitPath(path), !strings.Contains(path, `\`), tagNames)
+}
+
+// resolveSegments resolves the segments of a path against v, trying the rest of the path
+// as a literal key of the maps on the way when literal is true
+func resolveSegments(v reflect.Value, segments []string, literal bool, tagNames bool) reflect.Value {
+	for i, segment := range segments {
+		if literal && i < len(segments)-1 {
+			if m := indirect(v); m.Kind() == reflect.Map {
+				if found := fieldValue(m, strings.Join(segments[i:], "."), tagNames); found.IsValid() {
+					return found
+				}
+			}
+		}
+		if v = fieldValue(v, segment, tagNames); !v.IsValid() {
+			break
+		}
+	}
+	return v
+}
+
+// fieldValue resolves a single path segment against v, following pointers and interfaces.
+// Structs are looked up by field name (see structField), maps by key and slices or arrays by index.
+func fieldValue(v reflect.Value, name string, tagNames bool) reflect.Value {
+	v = indirect(v)
+
+	switch v.Kind() {
+	case reflect.Map:
+		key, ok := mapKey(v.Type().Key(), name)
+		if !ok {
+			return reflect.Value{}
+		}
+		return v.MapIndex(key)
+
+	case reflect.Struct:
+		return structField(v, name, tagNames)
+
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(name)
+		if err != nil || i < 0 || i >= v.Len() {
+			return reflect.Value{}
+		}
+		return v.Index(i)
+
+	default:
+		return reflect.Value{}
+	}
+}
+
+// indirect dereferences pointers and interfaces, returning an invalid value on nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// mapKey converts a path segment into a key of the given map key type
+func mapKey(keyType reflect.Type, name string) (reflect.Value, bool) {
+	switch keyType.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(name).Convert(keyType), true
+	case reflect.Interface:
+		return reflect.ValueOf(name), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(name, 10, keyType.Bits())
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(i).Convert(keyType), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(name, 10, keyType.Bits())
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(u).Convert(keyType), true
+	default:
+		return reflect.Value{}, false
+	}
+}
@@ -0,0 +1,30 @@
+package fq
+
+// Fields maps the keys of a projection to the field names or dot paths they're read from,
+// an empty path selects the item itself
+type Fields map[string]string
+
+// Project returns the fields of item, renamed to their keys in fields.
+// Fields missing from item are set to nil.
+func Project(item interface{}, fields Fields) map[string]interface{} {
+	result := make(map[string]interface{}, len(fields))
+	for key, path := range fields {
+		result[key] = fieldOrItem(item, path)
+	}
+	return result
+}
+
+// ProjectC projects the items of input (see Project).
+// The output channel is closed once input is closed.
+func ProjectC[T any](input <-chan T, fields Fields) <-chan map[string]interface{} {
+	output := make(chan map[string]interface{})
+
+	go func() {
+		defer close(output)
+		for item := range input {
+			output <- Project(item, fields)
+		}
+	}()
+
+	return output
+}
@@ -0,0 +1,64 @@
+package fq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	product := getTestProducts()[0]
+
+	result := Project(product, Fields{
+		"name":    "Name",
+		"city":    "Address.City",
+		"country": "manufacturer.country",
+		"tag":     "Tags.1",
+		"missing": "Address.Zip",
+	})
+
+	expected := map[string]interface{}{
+		"name":    "Laptop Pro",
+		"city":    "San Francisco",
+		"country": nil,
+		"tag":     "work",
+		"missing": nil,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	record := map[string]interface{}{
+		"user": map[string]interface{}{"email": "ana@example.com"},
+		"id":   float64(7),
+	}
+	result = Project(record, Fields{"email": "user.email", "id": "id", "self": ""})
+	if result["email"] != "ana@example.com" || result["id"] != float64(7) || !reflect.DeepEqual(result["self"], record) {
+		t.Errorf("Unexpected projection of a map: %v", result)
+	}
+
+	if result := Project(nil, Fields{"a": "b"}); len(result) != 1 || result["a"] != nil {
+		t.Errorf("Expected nil fields for a nil item, got %v", result)
+	}
+}
+
+func TestProjectC(t *testing.T) {
+	input := make(chan Product)
+	go func() {
+		defer close(input)
+		for _, product := range getTestProducts() {
+			input <- product
+		}
+	}()
+
+	var names []interface{}
+	for row := range ProjectC(input, Fields{"name": "Name"}) {
+		if len(row) != 1 {
+			t.Errorf("Expected a single field, got %v", row)
+		}
+		names = append(names, row["name"])
+	}
+
+	if len(names) != 5 || names[0] != "Laptop Pro" {
+		t.Errorf("Expected 5 names starting with Laptop Pro, got %v", names)
+	}
+}
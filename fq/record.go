@@ -0,0 +1,153 @@
+package fq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"reflect"
+)
+
+// Record is a value read from a source along with where it was read. Queries are evaluated
+// against Value: filtering Records with Q{"level": "error"} matches the Records whose Value
+// has a level field equal to "error", and the Records are passed through as they are.
+// Items that are Records or *Records, or interfaces holding them, are filtered, compiled,
+// validated, explained, sorted, grouped and projected by their Value, a nil *Record having
+// a nil Value.
+type Record struct {
+	Value  interface{}
+	File   string // path of the file, empty for readers
+	Line   int    // line number, from 1
+	Offset int64  // byte offset of the start of the line, in the decompressed content
+}
+
+// ErrJSONArray is reported by JSONFileRecordSeq and JSONReaderRecordSeq for JSON arrays,
+// whose elements have no line
+var ErrJSONArray = errors.New("JSON array input, expected JSONL")
+
+var (
+	recordType    = reflect.TypeOf(Record{})
+	recordPtrType = reflect.TypeOf(&Record{})
+)
+
+// recordValue returns the value queries are evaluated against for an item, the Value of a
+// Record or *Record
+func recordValue(item interface{}) interface{} {
+	switch r := item.(type) {
+	case Record:
+		return r.Value
+	case *Record:
+		if r == nil {
+			return nil
+		}
+		return r.Value
+	}
+	return item
+}
+
+// JSONLFileRecordStream creates a channel of the Records of the objects parsed from a JSONL
+// file, like JSONLFileSourceStream, and a channel for errors
+func JSONLFileRecordStream(filePath string, opts ...SourceOption) (<-chan Record, <-chan error) {
+	output := make(chan Record, 100)
+	errCh := make(chan error, 10)
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		for record, err := range JSONLFileRecordSeq(filePath, opts...) {
+			if err != nil {
+				errCh <- err
+				continue
+			}
+			output <- record
+		}
+	}()
+
+	return output, errCh
+}
+
+// JSONLFileRecordSeq iterates over the Records of the objects parsed from a JSONL file like
+// JSONLFileSourceSeq, yielding errors along with the zero Record
+func JSONLFileRecordSeq(filePath string, opts ...SourceOption) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			yield(Record{}, fmt.Errorf("failed to open file: %w", err))
+			return
+		}
+		defer file.Close()
+
+		if err := readRecords(file, filePath, newSourceOptions(opts), false, yield); err != nil {
+			yield(Record{}, fmt.Errorf("error reading file: %w", err))
+		}
+	}
+}
+
+// JSONLReaderRecordSeq iterates over the Records of the objects parsed from the JSONL read
+// from r like JSONLReaderSourceSeq, File being empty. r isn't closed.
+func JSONLReaderRecordSeq(r io.Reader, opts ...SourceOption) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		if err := readRecords(r, "", newSourceOptions(opts), false, yield); err != nil {
+			yield(Record{}, fmt.Errorf("error reading input: %w", err))
+		}
+	}
+}
+
+// JSONFileRecordSeq iterates over the Records of a JSONL file like JSONLFileRecordSeq, except
+// for the files JSONFileSourceSeq reads as a JSON array, starting with [ or with WithArrayPath
+// set, which are reported with ErrJSONArray
+func JSONFileRecordSeq(filePath string, opts ...SourceOption) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			yield(Record{}, fmt.Errorf("failed to open file: %w", err))
+			return
+		}
+		defer file.Close()
+
+		if err := readRecords(file, filePath, newSourceOptions(opts), true, yield); err != nil {
+			yield(Record{}, fmt.Errorf("error reading file: %w", err))
+		}
+	}
+}
+
+// JSONReaderRecordSeq iterates over the Records of the JSONL read from r like
+// JSONLReaderRecordSeq, reporting JSON arrays with ErrJSONArray like JSONFileRecordSeq
+func JSONReaderRecordSeq(r io.Reader, opts ...SourceOption) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		if err := readRecords(r, "", newSourceOptions(opts), true, yield); err != nil {
+			yield(Record{}, fmt.Errorf("error reading input: %w", err))
+		}
+	}
+}
+
+// readRecords yields the Records of the objects decoded from the lines of r, the content of
+// the file name if any, until yield returns false, returning the read error if any.
+// With arrays, the input read as a JSON array by readJSON is reported with ErrJSONArray.
+func readRecords(r io.Reader, name string, o *sourceOptions, arrays bool, yield func(Record, error) bool) error {
+	reader, err := decompress(r, name)
+	if err != nil {
+		return err
+	}
+
+	var lines int
+	var offset int64
+	if arrays {
+		var b byte
+		b, lines, offset, err = firstByte(reader)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if o.arrayPath != "" || err == nil && b == '[' {
+			return ErrJSONArray
+		}
+	}
+	return readPositions(reader, lines, offset, o, func(obj interface{}, line int, offset int64, err error) bool {
+		if err != nil {
+			return yield(Record{}, err)
+		}
+		return yield(Record{Value: obj, File: name, Line: line, Offset: offset}, nil)
+	})
+}
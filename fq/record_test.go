@@ -0,0 +1,175 @@
+package fq
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONLRecordSource(t *testing.T) {
+	content := "{\"id\": 1, \"level\": \"info\"}\r\n\n{\"id\": 2, \"level\": \"error\"}\ninvalid\n{\"id\": 3, \"level\": \"error\"}"
+	path := filepath.Join(t.TempDir(), "app.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	var records []Record
+	var errs []error
+	for record, err := range JSONLFileRecordSeq(path) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "line 4: error parsing JSON") {
+		t.Errorf("Expected a parse error on line 4, got %v", errs)
+	}
+	lines := []int{1, 3, 5}
+	if len(records) != len(lines) {
+		t.Fatalf("Expected %d records, got %v", len(lines), records)
+	}
+	for i, record := range records {
+		if record.File != path || record.Line != lines[i] {
+			t.Errorf("Expected %s line %d, got %s line %d", path, lines[i], record.File, record.Line)
+		}
+		if want := `{"id": ` + string(rune('1'+i)); !strings.HasPrefix(content[record.Offset:], want) {
+			t.Errorf("Expected offset %d to start %q, got %q", record.Offset, want, content[record.Offset:])
+		}
+	}
+
+	t.Run("queries evaluate Value", func(t *testing.T) {
+		query := Q{"level": "error", "id": Gt(2)}
+		expected := []Record{records[2]}
+
+		matches, err := Filter(records, query, 0, 0)
+		if err != nil || !reflect.DeepEqual(matches, expected) {
+			t.Errorf("Filter: expected %v, got %v (%v)", expected, matches, err)
+		}
+
+		input, _ := JSONLFileRecordStream(path)
+		resultCh, errCh := FilterC(input, query, 0, 0)
+		var results []Record
+		for record := range resultCh {
+			results = append(results, record)
+		}
+		if err := <-errCh; err != nil || !reflect.DeepEqual(results, expected) {
+			t.Errorf("FilterC: expected %v, got %v (%v)", expected, results, err)
+		}
+
+		compiled, err := Compile[Record](query)
+		if err != nil {
+			t.Fatalf("Compile: unexpected error: %v", err)
+		}
+		if matches, _ := compiled.Filter(records, 0, 0); !reflect.DeepEqual(matches, expected) {
+			t.Errorf("Compiled: expected %v, got %v", expected, matches)
+		}
+
+		if explanation := Explain(query, records[1]); explanation.Result || explanation.Value == nil {
+			t.Errorf("Explain: expected the Value of record 2 not to match, got %s", explanation)
+		}
+	})
+
+	t.Run("pointers and interfaces", func(t *testing.T) {
+		query := Q{"level": "error", "id": Gt(2)}
+		pointers := []*Record{nil, &records[0], &records[2]}
+		items := []interface{}{records[0], &records[2], records[2]}
+
+		if matches, err := Filter(pointers, query, 0, 0); err != nil || !reflect.DeepEqual(matches, pointers[2:]) {
+			t.Errorf("Filter *Record: expected %v, got %v (%v)", pointers[2:], matches, err)
+		}
+		if matches, err := Filter(pointers, Q{"": nil}, 0, 0); err != nil || !reflect.DeepEqual(matches, pointers[:1]) {
+			t.Errorf("Filter *Record: expected the nil *Record to have a nil Value, got %v (%v)", matches, err)
+		}
+		if matches, err := Filter(items, query, 0, 0); err != nil || !reflect.DeepEqual(matches, items[1:]) {
+			t.Errorf("Filter interface: expected %v, got %v (%v)", items[1:], matches, err)
+		}
+
+		compiled, err := Compile[*Record](query)
+		if err != nil {
+			t.Fatalf("Compile: unexpected error: %v", err)
+		}
+		if !compiled.Match(&records[2]) || compiled.Match(&records[1]) || compiled.Match(nil) {
+			t.Error("Compiled: expected only record 3 to match")
+		}
+
+		for _, sampleType := range []reflect.Type{reflect.TypeOf(Record{}), reflect.TypeOf(&Record{})} {
+			if err := Validate(query, sampleType); err != nil {
+				t.Errorf("Validate %v: unexpected error: %v", sampleType, err)
+			}
+			if err := Validate(Q{"id": Gt(true)}, sampleType); err == nil {
+				t.Errorf("Validate %v: expected an invalid operand error", sampleType)
+			}
+		}
+
+		if explanation := Explain(query, &records[2]); !explanation.Result {
+			t.Errorf("Explain: expected the Value of record 3 to match, got %s", explanation)
+		}
+	})
+
+	t.Run("sort, group and project", func(t *testing.T) {
+		sorted := []*Record{&records[0], &records[1], &records[2]}
+		Sort(sorted, By("id", Desc))
+		if sorted[0] != &records[2] || sorted[2] != &records[0] {
+			t.Errorf("Sort: expected records by descending id, got %v", sorted)
+		}
+
+		groups := GroupBy(records, "level", Count())
+		expected := []map[string]interface{}{{"level": "info", "count": 1}, {"level": "error", "count": 2}}
+		if !reflect.DeepEqual(groups, expected) {
+			t.Errorf("GroupBy: expected %v, got %v", expected, groups)
+		}
+
+		projected := Project(&records[1], Fields{"id": "id", "record": ""})
+		if projected["id"] != 2.0 || !reflect.DeepEqual(projected["record"], records[1].Value) {
+			t.Errorf("Project: expected the fields of the Value of record 2, got %v", projected)
+		}
+	})
+
+	t.Run("readers", func(t *testing.T) {
+		var lines []int
+		for record, err := range JSONLReaderRecordSeq(strings.NewReader("1\n\n2\n")) {
+			if err != nil || record.File != "" {
+				t.Fatalf("Unexpected record %v, %v", record, err)
+			}
+			lines = append(lines, record.Line)
+		}
+		if !reflect.DeepEqual(lines, []int{1, 3}) {
+			t.Errorf("Expected lines 1 and 3, got %v", lines)
+		}
+	})
+
+	t.Run("json arrays", func(t *testing.T) {
+		for _, input := range []string{"  [\n{\"id\": 1}\n]", "[1, 2]\n"} {
+			var errs []error
+			for _, err := range JSONReaderRecordSeq(strings.NewReader(input)) {
+				errs = append(errs, err)
+			}
+			if len(errs) != 1 || !errors.Is(errs[0], ErrJSONArray) {
+				t.Errorf("%q: expected ErrJSONArray, got %v", input, errs)
+			}
+		}
+		for _, err := range JSONFileRecordSeq(path, WithArrayPath(".items")) {
+			if !errors.Is(err, ErrJSONArray) {
+				t.Errorf("Expected ErrJSONArray with an array path, got %v", err)
+			}
+		}
+
+		// JSONL positions count the leading spaces skipped while looking for [
+		spaces := strings.Repeat("\n", 5000) + "  "
+		var got []Record
+		for record, err := range JSONReaderRecordSeq(strings.NewReader(spaces + "{\"id\": 1}\n")) {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got = append(got, record)
+		}
+		if len(got) != 1 || got[0].Line != 5001 || got[0].Offset != 5000 {
+			t.Errorf("Expected line 5001 at offset 5000, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package fq
+
+import (
+	"iter"
+)
+
+// FilterSeq filters the items of seq like FilterC, without goroutines or channels.
+// Items whose evaluation panics are dropped, and a query that fails Validate for T yields
+// nothing: use FilterSeq2 to get those errors.
+func FilterSeq[T any](seq iter.Seq[T], query Query, skip int, limit int, opts ...Option) iter.Seq[T] {
+	return withoutErrors(FilterSeq2(withErrors(seq), query, skip, limit, opts...))
+}
+
+// FilterSeq2 filters the items of seq like FilterC, without goroutines or channels.
+// Errors of seq are passed through, and evaluation errors are yielded along with a
+// zero T. A query that fails Validate for T only yields the validation error.
+// Like Filter, a nil query matches every item.
+func FilterSeq2[T any](seq iter.Seq2[T, error], query Query, skip int, limit int, opts ...Option) iter.Seq2[T, error] {
+	o := newOptions(opts)
+	match, err := compileMatch[T](query, o)
+	if err != nil {
+		return failedSeq[T](err, o)
+	}
+	if query == nil {
+		// like Filter, a nil query matches everything
+		match = func(T) bool { return true }
+	}
+
+	return filterSeq(seq, match, skip, limit, o)
+}
+
+// filterSeq yields the items of seq for which match is true
+func filterSeq[T any](seq iter.Seq2[T, error], match func(T) bool, skip int, limit int, o *options) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		obs := newObserver[T](o)
+		defer obs.complete(nil)
+
+		var zero T
+		matched := 0
+		sent := 0
+
+		for item, err := range seq {
+			if err != nil {
+				if !yield(zero, err) {
+					return
+				}
+				continue
+			}
+
+			var matches bool
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						obs.panicked(&item, r)
+						err = recoveredError(r, "panic during filter evaluation")
+						matches = false
+					}
+				}()
+				start := obs.begin()
+				matches = match(item)
+				obs.evaluated(&item, matches, start)
+			}()
+
+			if err != nil {
+				if !yield(zero, err) {
+					return
+				}
+				continue
+			}
+
+			if !matches {
+				continue
+			}
+
+			matched++
+			if matched <= skip {
+				obs.skipped(&item)
+				continue
+			}
+
+			more := yield(item, nil)
+			sent++
+			obs.returned()
+
+			if limit > 0 && sent >= limit {
+				obs.limitReached(limit)
+				return
+			}
+			if !more {
+				return
+			}
+		}
+	}
+}
+
+// failedSeq returns a sequence yielding only err, completing the observer of o with it
+func failedSeq[T any](err error, o *options) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		obs := newObserver[T](o)
+		defer obs.complete(err)
+
+		var zero T
+		yield(zero, err)
+	}
+}
+
+// withErrors adapts seq to the FilterSeq2 input, with no errors
+func withErrors[T any](seq iter.Seq[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for item := range seq {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// withoutErrors yields the items of seq, dropping its errors
+func withoutErrors[T any](seq iter.Seq2[T, error]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item, err := range seq {
+			if err != nil {
+				continue
+			}
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
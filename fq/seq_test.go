@@ -0,0 +1,129 @@
+package fq
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestFilterSeq(t *testing.T) {
+	products := getTestProducts()
+
+	tests := []struct {
+		name  string
+		query Query
+		skip  int
+		limit int
+	}{
+		{"nil query", nil, 0, 0},
+		{"price", Q{"Price": Lt(500)}, 0, 0},
+		{"skip", Q{"InStock": true}, 2, 0},
+		{"limit", Q{"InStock": true}, 0, 2},
+		{"skip and limit", Q{"InStock": true}, 1, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := Filter(products, tc.query, tc.skip, tc.limit)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result := slices.Collect(FilterSeq(slices.Values(products), tc.query, tc.skip, tc.limit))
+
+			compiled, err := Compile[Product](tc.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			compiledResult := slices.Collect(compiled.FilterSeq(slices.Values(products), tc.skip, tc.limit))
+
+			if len(result) != len(expected) || len(compiledResult) != len(expected) {
+				t.Fatalf("Expected %d products, got %d and %d compiled", len(expected), len(result), len(compiledResult))
+			}
+			for i := range result {
+				if result[i].ID != expected[i].ID || compiledResult[i].ID != expected[i].ID {
+					t.Errorf("Expected product %d at %d, got %d and %d compiled", expected[i].ID, i, result[i].ID, compiledResult[i].ID)
+				}
+			}
+		})
+	}
+
+	t.Run("stops pulling items", func(t *testing.T) {
+		pulled := 0
+		seq := func(yield func(int) bool) {
+			for i := 0; ; i++ {
+				pulled++
+				if !yield(i) {
+					return
+				}
+			}
+		}
+
+		for v := range FilterSeq(seq, Gt(10), 0, 0) {
+			if v == 12 {
+				break
+			}
+		}
+		if pulled != 13 {
+			t.Errorf("Expected 13 items pulled after break, got %d", pulled)
+		}
+
+		pulled = 0
+		result := slices.Collect(FilterSeq(seq, Gte(0), 0, 3))
+		if len(result) != 3 || pulled != 3 {
+			t.Errorf("Expected 3 items pulled for limit 3, got %v after %d", result, pulled)
+		}
+	})
+
+	t.Run("invalid query yields nothing", func(t *testing.T) {
+		result := slices.Collect(FilterSeq(slices.Values(products), Q{"Nme": "x"}, 0, 0))
+		if len(result) != 0 {
+			t.Errorf("Expected no results, got %v", result)
+		}
+	})
+}
+
+func TestFilterSeq2(t *testing.T) {
+	errSource := errors.New("bad record")
+	var seq iter.Seq2[interface{}, error] = func(yield func(interface{}, error) bool) {
+		_ = yield(map[string]interface{}{"id": 1}, nil) &&
+			yield(nil, errSource) &&
+			yield("not a map", nil) &&
+			yield(map[string]interface{}{"id": 2}, nil) &&
+			yield(map[string]interface{}{"id": 3}, nil)
+	}
+
+	// panics on the string record
+	query := Q{"": func(v interface{}) bool {
+		return v.(map[string]interface{})["id"].(int) >= 2
+	}}
+
+	var results []interface{}
+	var errs []error
+	for item, err := range FilterSeq2(seq, query, 0, 0) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		results = append(results, item)
+	}
+
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results, got %v", results)
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], errSource) {
+		t.Errorf("Expected the source error then the evaluation error, got %v", errs)
+	}
+
+	t.Run("invalid query", func(t *testing.T) {
+		var errs []error
+		for _, err := range FilterSeq2(withErrors(slices.Values(getTestProducts())), Q{"Nme": "x"}, 0, 0) {
+			errs = append(errs, err)
+		}
+		var unknown *ErrUnknownField
+		if len(errs) != 1 || !errors.As(errs[0], &unknown) {
+			t.Errorf("Expected a single ErrUnknownField, got %v", errs)
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package fq
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// Direction is the direction of a sort key
+type Direction int
+
+const (
+	Asc  Direction = iota // smallest values first, nil first
+	Desc                  // largest values first, nil last
+)
+
+// SortKey orders items by the value of a field (see By)
+type SortKey struct {
+	Field     string // field name or dot path, empty for the item itself
+	Direction Direction
+}
+
+// By returns a sort key ordering items by field in direction
+func By(field string, direction Direction) SortKey {
+	return SortKey{Field: field, Direction: direction}
+}
+
+// Sort sorts data in place by keys, the first key taking precedence.
+// Values compare like they do in Gt and Lt, values that can't be compared with each other
+// are ordered by type: nil, numbers, strings, times, booleans, then anything else.
+// The sort is stable, so items with equal keys keep their order.
+func Sort[T any](data []T, keys ...SortKey) {
+	entries := sortEntries(data, keys)
+	slices.SortStableFunc(entries, func(a, b sortEntry[T]) int {
+		return compareEntries(a, b, keys)
+	})
+	for i, entry := range entries {
+		data[i] = entry.item
+	}
+}
+
+// FilterSorted filters data like Filter, then sorts the results by keys (see Sort)
+// before applying skip and limit. data itself is left unchanged.
+func FilterSorted[T any](data []T, query Query, keys []SortKey, skip int, limit int, opts ...Option) ([]T, error) {
+	result, err := Filter(data, query, 0, 0, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	result = slices.Clone(result)
+	Sort(result, keys...)
+	return paginate(result, skip, limit), nil
+}
+
+// FilterCSorted filters data like FilterC, sending the results sorted by keys (see Sort)
+// once input is closed, with skip and limit applied after sorting.
+// With a limit, only the best skip+limit results are kept in memory; without one every
+// result is buffered until input is closed.
+// Hooks observe the filtering, before results are sorted and paginated.
+func FilterCSorted[T any](input <-chan T, query Query, keys []SortKey, skip int, limit int, opts ...Option) (<-chan T, <-chan error) {
+	return FilterCSortedCtx(context.Background(), input, query, keys, skip, limit, opts...)
+}
+
+// FilterCSortedCtx filters data like FilterCSorted but stops as soon as ctx is done (see FilterCCtx)
+func FilterCSortedCtx[T any](ctx context.Context, input <-chan T, query Query, keys []SortKey, skip int, limit int, opts ...Option) (<-chan T, <-chan error) {
+	matches, errCh := FilterCCtx(ctx, input, query, 0, 0, opts...)
+	output := make(chan T)
+
+	go func() {
+		defer close(output)
+
+		bound := 0
+		if limit > 0 {
+			bound = skip + limit
+		}
+		top := &topK[T]{keys: keys, bound: bound}
+
+		for item := range matches {
+			top.add(item)
+		}
+
+		for _, item := range paginate(top.sorted(), skip, 0) {
+			select {
+			case output <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return output, errCh
+}
+
+// sortEntry is an item with the values of its sort keys
+type sortEntry[T any] struct {
+	item   T
+	values []interface{}
+	seq    int
+}
+
+func newSortEntry[T any](item T, keys []SortKey, seq int) sortEntry[T] {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = fieldOrItem(item, key.Field)
+	}
+	return sortEntry[T]{item: item, values: values, seq: seq}
+}
+
+func sortEntries[T any](data []T, keys []SortKey) []sortEntry[T] {
+	entries := make([]sortEntry[T], len(data))
+	for i, item := range data {
+		entries[i] = newSortEntry(item, keys, i)
+	}
+	return entries
+}
+
+func compareEntries[T any](a, b sortEntry[T], keys []SortKey) int {
+	for i, key := range keys {
+		c := orderValues(a.values[i], b.values[i])
+		if key.Direction == Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// orderValues is a total order for sorting: values compare like in compareValues,
+// values that can't be compared with each other are ordered by sortRank
+func orderValues(a, b interface{}) int {
+	if c, ok := compare(a, b); ok {
+		return c
+	}
+
+	ra, rb := sortRank(a), sortRank(b)
+	if ra != rb {
+		return cmp.Compare(ra, rb)
+	}
+
+	switch ra {
+	case rankString:
+		// named string types
+		return cmp.Compare(reflect.ValueOf(a).String(), reflect.ValueOf(b).String())
+	case rankBool:
+		x, y := reflect.ValueOf(a).Bool(), reflect.ValueOf(b).Bool()
+		if x == y {
+			return 0
+		} else if !x {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+const (
+	rankNil = iota
+	rankNumber
+	rankString
+	rankTime
+	rankBool
+	rankOther
+)
+
+func sortRank(v interface{}) int {
+	if v == nil {
+		return rankNil
+	}
+	if _, ok := toNumber(v); ok {
+		return rankNumber
+	}
+	if _, ok := v.(time.Time); ok {
+		return rankTime
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String:
+		return rankString
+	case reflect.Bool:
+		return rankBool
+	default:
+		return rankOther
+	}
+}
+
+// paginate applies skip and limit to result
+func paginate[T any](result []T, skip int, limit int) []T {
+	result = result[min(skip, len(result)):]
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+	return result
+}
+
+// topK keeps the first bound items added in sort order (all of them when bound is 0).
+// It's a heap with the last of the kept items on top, so it can be replaced in O(log bound).
+type topK[T any] struct {
+	keys    []SortKey
+	bound   int
+	entries []sortEntry[T]
+	seq     int
+}
+
+func (t *topK[T]) add(item T) {
+	entry := newSortEntry(item, t.keys, t.seq)
+	t.seq++
+
+	if t.bound == 0 {
+		t.entries = append(t.entries, entry)
+		return
+	}
+	if len(t.entries) < t.bound {
+		heap.Push(t, entry)
+		return
+	}
+	if t.before(entry, t.entries[0]) {
+		t.entries[0] = entry
+		heap.Fix(t, 0)
+	}
+}
+
+// sorted returns the kept items in sort order
+func (t *topK[T]) sorted() []T {
+	slices.SortFunc(t.entries, func(a, b sortEntry[T]) int {
+		if t.before(a, b) {
+			return -1
+		}
+		return 1
+	})
+	result := make([]T, len(t.entries))
+	for i, entry := range t.entries {
+		result[i] = entry.item
+	}
+	return result
+}
+
+// before reports whether a sorts before b, ties broken by arrival order to keep the sort stable
+func (t *topK[T]) before(a, b sortEntry[T]) bool {
+	if c := compareEntries(a, b, t.keys); c != 0 {
+		return c < 0
+	}
+	return a.seq < b.seq
+}
+
+func (t *topK[T]) Len() int           { return len(t.entries) }
+func (t *topK[T]) Less(i, j int) bool { return t.before(t.entries[j], t.entries[i]) }
+func (t *topK[T]) Swap(i, j int)      { t.entries[i], t.entries[j] = t.entries[j], t.entries[i] }
+func (t *topK[T]) Push(x interface{}) { t.entries = append(t.entries, x.(sortEntry[T])) }
+func (t *topK[T]) Pop() interface{} {
+	last := t.entries[len(t.entries)-1]
+	t.entries = t.entries[:len(t.entries)-1]
+	return last
+}
@@ -0,0 +1,155 @@
+package fq
+
+import (
+	"testing"
+)
+
+func productIDs(products []Product) []int {
+	ids := make([]int, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	return ids
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []SortKey
+		expected []int
+	}{
+		{"price ascending", []SortKey{By("Price", Asc)}, []int{5, 4, 2, 3, 1}},
+		{"price descending", []SortKey{By("Price", Desc)}, []int{1, 3, 2, 4, 5}},
+		{"multiple keys", []SortKey{By("InStock", Desc), By("Rating", Asc)}, []int{2, 4, 1, 3, 5}},
+		{"path", []SortKey{By("Address.City", Asc)}, []int{3, 4, 5, 1, 2}},
+		{"no keys keeps order", nil, []int{1, 2, 3, 4, 5}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			products := getTestProducts()
+			Sort(products, tc.keys...)
+			if ids := productIDs(products); !equalInts(ids, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+
+	t.Run("mixed types", func(t *testing.T) {
+		items := []map[string]interface{}{
+			{"id": 1, "v": true},
+			{"id": 2, "v": "b"},
+			{"id": 3, "v": 2},
+			{"id": 4},
+			{"id": 5, "v": 1.5},
+			{"id": 6, "v": "a"},
+			{"id": 7, "v": nil},
+			{"id": 8, "v": false},
+		}
+
+		Sort(items, By("v", Asc))
+
+		expected := []int{4, 7, 5, 3, 6, 2, 8, 1}
+		for i, item := range items {
+			if item["id"] != expected[i] {
+				t.Fatalf("Expected ids %v, got %v", expected, items)
+			}
+		}
+	})
+}
+
+func TestFilterSorted(t *testing.T) {
+	products := getTestProducts()
+
+	result, err := FilterSorted(products, Q{"InStock": true}, []SortKey{By("Price", Asc)}, 1, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids := productIDs(result); !equalInts(ids, []int{2, 3}) {
+		t.Errorf("Expected [2 3], got %v", ids)
+	}
+
+	result, err = FilterSorted(products, nil, []SortKey{By("Price", Desc)}, 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids := productIDs(result); !equalInts(ids, []int{1, 3, 2, 4, 5}) {
+		t.Errorf("Expected [1 3 2 4 5], got %v", ids)
+	}
+	if ids := productIDs(products); !equalInts(ids, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected data to be left unchanged, got %v", ids)
+	}
+
+	if _, err := FilterSorted(products, Q{"Nme": "x"}, nil, 0, 0); err == nil {
+		t.Error("Expected a validation error")
+	}
+}
+
+func TestFilterCSorted(t *testing.T) {
+	products := getManyTestProducts(1000)
+	keys := []SortKey{By("Rating", Desc), By("Price", Asc)}
+	query := Q{"InStock": true}
+
+	tests := []struct {
+		name  string
+		skip  int
+		limit int
+	}{
+		{"top 10", 0, 10},
+		{"page", 20, 10},
+		{"no limit", 5, 0},
+		{"limit past results", 0, 2000},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := FilterSorted(products, query, keys, tc.skip, tc.limit)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			input := make(chan Product)
+			go func() {
+				defer close(input)
+				for _, product := range products {
+					input <- product
+				}
+			}()
+
+			var result []Product
+			dataCh, errCh := FilterCSorted(input, query, keys, tc.skip, tc.limit)
+			for product := range dataCh {
+				result = append(result, product)
+			}
+			for err := range errCh {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if ids := productIDs(result); !equalInts(ids, productIDs(expected)) {
+				t.Errorf("Expected %v, got %v", productIDs(expected), ids)
+			}
+		})
+	}
+
+	t.Run("bounded", func(t *testing.T) {
+		top := &topK[Product]{keys: keys, bound: 5}
+		for _, product := range products {
+			top.add(product)
+		}
+		if len(top.entries) != 5 {
+			t.Errorf("Expected 5 kept items, got %d", len(top.entries))
+		}
+	})
+}
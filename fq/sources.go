@@ -2,57 +2,291 @@ package fq
 
 import (
 	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
+// ErrLineTooLong is reported for the lines longer than the WithMaxLineSize limit
+var ErrLineTooLong = errors.New("line too long")
+
+// SourceOption configures a JSONL source
+type SourceOption func(*sourceOptions)
+
+type sourceOptions struct {
+	maxLineSize           int
+	disallowUnknownFields bool
+	arrayPath             string
+	fromStart             bool
+	pollInterval          time.Duration
+}
+
+// WithMaxLineSize limits lines to n bytes, line ending excluded. Longer lines are skipped
+// and reported as errors wrapping ErrLineTooLong. Lines have no size limit by default.
+// The elements of JSON arrays are limited the same way, once they're read.
+func WithMaxLineSize(n int) SourceOption {
+	return func(o *sourceOptions) {
+		o.maxLineSize = n
+	}
+}
+
+// DisallowUnknownFields reports the objects with fields that don't match a field of the
+// struct they're decoded into as errors, like json.Decoder.DisallowUnknownFields
+func DisallowUnknownFields() SourceOption {
+	return func(o *sourceOptions) {
+		o.disallowUnknownFields = true
+	}
+}
+
+func newSourceOptions(opts []SourceOption) *sourceOptions {
+	o := &sourceOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
 // JSONLFileSourceStream creates a channel of objects parsed from a JSONL file and a channel for errors.
-func JSONLFileSourceStream(filePath string) (<-chan interface{}, <-chan error) {
+func JSONLFileSourceStream(filePath string, opts ...SourceOption) (<-chan interface{}, <-chan error) {
 	output := make(chan interface{}, 100)
 	errCh := make(chan error, 10)
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		go func() {
-			errCh <- fmt.Errorf("failed to open file: %w", err)
-			close(errCh)
-			close(output)
-		}()
-		return output, errCh
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		for obj, err := range JSONLFileSourceSeq(filePath, opts...) {
+			if err != nil {
+				errCh <- err
+				continue
+			}
+			output <- obj
+		}
+	}()
+
+	return output, errCh
+}
+
+// JSONLFileSourceSeq iterates over the objects parsed from a JSONL file, yielding parse
+// errors along with a nil object. The file is opened when iteration starts and closed
+// when it stops. gzip and bzip2 files are decompressed, detected by their magic bytes
+// or their .gz or .bz2 extension.
+func JSONLFileSourceSeq(filePath string, opts ...SourceOption) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to open file: %w", err))
+			return
+		}
+		defer file.Close()
+
+		if err := readJSONL(file, filePath, newSourceOptions(opts), yield); err != nil {
+			yield(nil, fmt.Errorf("error reading file: %w", err))
+		}
 	}
+}
+
+// JSONLReaderSourceStream creates a channel of objects parsed from the JSONL read from r
+// and a channel for errors. r isn't closed.
+func JSONLReaderSourceStream(r io.Reader, opts ...SourceOption) (<-chan interface{}, <-chan error) {
+	return JSONLSource[interface{}](r, opts...)
+}
+
+// JSONLReaderSourceSeq iterates over the objects parsed from the JSONL read from r, yielding
+// parse errors along with a nil object. gzip and bzip2 data is decompressed. r isn't closed,
+// reading it again only yields the objects after the ones already read.
+func JSONLReaderSourceSeq(r io.Reader, opts ...SourceOption) iter.Seq2[interface{}, error] {
+	return JSONLSourceSeq[interface{}](r, opts...)
+}
+
+// JSONLSource creates a channel of the lines of r decoded into T with encoding/json, ready to
+// be filtered with FilterC[T], and a channel for errors. Lines that can't be decoded into T
+// are reported as errors. r isn't closed.
+func JSONLSource[T any](r io.Reader, opts ...SourceOption) (<-chan T, <-chan error) {
+	output := make(chan T, 100)
+	errCh := make(chan error, 10)
 
 	go func() {
-		defer file.Close()
 		defer close(output)
 		defer close(errCh)
 
-		scanner := bufio.NewScanner(file)
-		lineNum := 0
+		for obj, err := range JSONLSourceSeq[T](r, opts...) {
+			if err != nil {
+				errCh <- err
+				continue
+			}
+			output <- obj
+		}
+	}()
+
+	return output, errCh
+}
 
-		for scanner.Scan() {
-			lineNum++
-			line := scanner.Text()
+// JSONLSourceSeq iterates over the lines of r decoded into T, yielding decoding errors
+// along with the zero T. r isn't closed.
+func JSONLSourceSeq[T any](r io.Reader, opts ...SourceOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		if err := readJSONL(r, "", newSourceOptions(opts), yield); err != nil {
+			var zero T
+			yield(zero, fmt.Errorf("error reading input: %w", err))
+		}
+	}
+}
 
-			if strings.TrimSpace(line) == "" {
-				continue
+// readJSONL yields the objects decoded from the lines of r, the content of the file name
+// if any, until yield returns false, returning the read error if any
+func readJSONL[T any](r io.Reader, name string, o *sourceOptions, yield func(T, error) bool) error {
+	reader, err := decompress(r, name)
+	if err != nil {
+		return err
+	}
+	return readLines(reader, 0, o, yield)
+}
+
+// readLines yields the objects decoded from the lines of reader until yield returns false,
+// returning the read error if any. Lines are numbered after the skipped lines already read.
+func readLines[T any](reader *bufio.Reader, skipped int, o *sourceOptions, yield func(T, error) bool) error {
+	return readPositions(reader, skipped, 0, o, func(obj T, _ int, _ int64, err error) bool {
+		return yield(obj, err)
+	})
+}
+
+// readPositions yields the objects decoded from the lines of reader along with their line
+// number and the byte offset of the line until yield returns false, returning the read error if any.
+// Lines are numbered after the skipped lines already read, and offsets after the offset
+// of reader.
+func readPositions[T any](reader *bufio.Reader, skipped int, offset int64, o *sourceOptions, yield func(obj T, line int, offset int64, err error) bool) error {
+	var line []byte
+	var zero T
+	lineNum := skipped
+
+	for {
+		var size int
+		var tooLong bool
+		var err error
+		line, size, tooLong, err = readLine(reader, line[:0], o.maxLineSize)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		lineNum++
+		start := offset
+		offset += int64(size)
+
+		if tooLong {
+			if !yield(zero, lineNum, start, fmt.Errorf("line %d: %w (max %d bytes)", lineNum, ErrLineTooLong, o.maxLineSize)) {
+				return nil
 			}
+			continue
+		}
 
-			var obj interface{}
-			if err := json.Unmarshal([]byte(line), &obj); err != nil {
-				errCh <- fmt.Errorf("line %d: error parsing JSON: %w", lineNum, err)
-				continue
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		obj, err := decodeLine[T](line, o)
+		if err != nil {
+			if !yield(zero, lineNum, start, fmt.Errorf("line %d: error parsing JSON: %w", lineNum, err)) {
+				return nil
 			}
+			continue
+		}
 
-			output <- obj
+		if !yield(obj, lineNum, start, nil) {
+			return nil
 		}
+	}
+}
+
+// decompress returns a reader of the content of r, decompressed when it's gzip or bzip2
+// data, detected by its magic bytes or the extension of the file name
+func decompress(r io.Reader, name string) (*bufio.Reader, error) {
+	buffered := bufio.NewReader(r)
+	// read errors are reported by the first read
+	magic, _ := buffered.Peek(3)
+	ext := strings.ToLower(filepath.Ext(name))
 
-		if err := scanner.Err(); err != nil {
-			errCh <- fmt.Errorf("error reading file: %w", err)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) || ext == ".gz":
+		gz, err := gzip.NewReader(buffered)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
 		}
-	}()
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReader(gz), nil
 
-	return output, errCh
+	case bytes.HasPrefix(magic, []byte("BZh")) || ext == ".bz2":
+		return bufio.NewReader(bzip2.NewReader(buffered)), nil
+	}
+
+	return buffered, nil
+}
+
+// decodeLine decodes the JSON value of line into a T
+func decodeLine[T any](line []byte, o *sourceOptions) (T, error) {
+	var obj T
+	if !o.disallowUnknownFields {
+		err := json.Unmarshal(line, &obj)
+		return obj, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&obj); err != nil {
+		return obj, err
+	}
+	// like json.Unmarshal, only allow one value
+	if _, err := decoder.Token(); err != io.EOF {
+		return obj, errors.New("invalid data after top-level value")
+	}
+	return obj, nil
 }
 
+// readLine appends the next line of reader to buf without its line ending, size being the
+// number of bytes read with the line ending. Lines longer than max bytes if max > 0 are read
+// through without being stored and reported as tooLong. err is io.EOF once there are no more lines.
+func readLine(reader *bufio.Reader, buf []byte, max int) (line []byte, size int, tooLong bool, err error) {
+	line = buf
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		size += len(chunk)
+
+		if !tooLong {
+			line = append(line, chunk...)
+			// keep room for a \r\n ending until the line is complete
+			if max > 0 && len(line) > max+2 {
+				tooLong = true
+				line = line[:0]
+			}
+		}
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && size == 0:
+			return line, 0, false, io.EOF
+		case err != nil && err != io.EOF:
+			return line, size, false, err
+		}
+
+		if !tooLong {
+			line = bytes.TrimSuffix(line, []byte("\n"))
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			tooLong = max > 0 && len(line) > max
+		}
+		return line, size, tooLong, nil
+	}
+}
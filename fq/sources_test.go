@@ -1,11 +1,16 @@
 package fq
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestJSONLFileSourceStream(t *testing.T) {
@@ -165,6 +170,214 @@ invalid json line
 	})
 }
 
+func TestJSONLFileSourceSeq(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "data.jsonl")
+	content := "{\"id\":1}\ninvalid json line\n\n{\"id\":2}\n{\"id\":3}\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	var ids []float64
+	var errs []error
+	for obj, err := range JSONLFileSourceSeq(testFile) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids = append(ids, obj.(map[string]interface{})["id"].(float64))
+		if len(ids) == 2 {
+			break
+		}
+	}
+
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Expected ids 1 and 2 before break, got %v", ids)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "line 2: error parsing JSON") {
+		t.Errorf("Expected a parse error on line 2, got %v", errs)
+	}
+
+	for _, err := range JSONLFileSourceSeq(filepath.Join(t.TempDir(), "nonexistent.jsonl")) {
+		if err == nil || !strings.Contains(err.Error(), "failed to open file") {
+			t.Errorf("Expected an open error, got %v", err)
+		}
+	}
+}
+
+func TestJSONLReaderSource(t *testing.T) {
+	content := "{\"id\":1}\ninvalid json line\n\n{\"id\":2}\n"
+
+	results, errs := collectResults(JSONLReaderSourceStream(strings.NewReader(content)))
+	if len(results) != 2 || results[1].(map[string]interface{})["id"] != float64(2) {
+		t.Errorf("Expected 2 objects, got %v", results)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "line 2: error parsing JSON") {
+		t.Errorf("Expected a parse error on line 2, got %v", errs)
+	}
+
+	reader := iotest.ErrReader(errors.New("broken pipe"))
+	for obj, err := range JSONLReaderSourceSeq(reader) {
+		if obj != nil || err == nil || err.Error() != "error reading input: broken pipe" {
+			t.Errorf("Expected a read error, got %v, %v", obj, err)
+		}
+	}
+}
+
+func TestJSONLLongLines(t *testing.T) {
+	long := `{"id":2,"payload":"` + strings.Repeat("x", 200*1024) + `"}`
+	content := "{\"id\":1}\r\n" + long + "\r\n{\"id\":3}\n" + long
+
+	results, errs := collectResults(JSONLReaderSourceStream(strings.NewReader(content)))
+	if len(errs) > 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+	if len(results) != 4 || len(results[1].(map[string]interface{})["payload"].(string)) != 200*1024 {
+		t.Fatalf("Expected 4 objects with the long ones intact, got %d", len(results))
+	}
+
+	var ids []float64
+	errs = nil
+	for obj, err := range JSONLReaderSourceSeq(strings.NewReader(content), WithMaxLineSize(1024)) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids = append(ids, obj.(map[string]interface{})["id"].(float64))
+	}
+	if !reflect.DeepEqual(ids, []float64{1, 3}) {
+		t.Errorf("Expected the short lines 1 and 3, got %v", ids)
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], ErrLineTooLong) ||
+		!strings.HasPrefix(errs[0].Error(), "line 2: ") || !strings.HasPrefix(errs[1].Error(), "line 4: ") {
+		t.Errorf("Expected ErrLineTooLong on lines 2 and 4, got %v", errs)
+	}
+
+	// the limit excludes line endings
+	results, errs = collectResults(JSONLReaderSourceStream(strings.NewReader("{\"id\":1}\r\n{\"id\":22}\n"), WithMaxLineSize(8)))
+	if len(results) != 1 || len(errs) != 1 || errs[0].Error() != "line 2: line too long (max 8 bytes)" {
+		t.Errorf("Expected line 2 only to be too long, got %v and %v", results, errs)
+	}
+}
+
+func TestJSONLSource(t *testing.T) {
+	type event struct {
+		ID    int      `json:"id"`
+		Level string   `json:"level"`
+		Tags  []string `json:"tags"`
+	}
+
+	content := `{"id": 1, "level": "error", "tags": ["db"]}
+{"id": "two", "level": "info"}
+{"id": 3, "level": "error", "host": "a"}
+{"id": 4, "level": "info", "tags": ["db"]} {"id": 5}
+`
+
+	query, err := Compile[event](Q{"Level": "error"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events, sourceErrs := JSONLSource[event](strings.NewReader(content))
+	matches, filterErrs := query.FilterC(events, 0, 0)
+
+	var ids []int
+	for e := range matches {
+		ids = append(ids, e.ID)
+	}
+	var errs []error
+	for err := range sourceErrs {
+		errs = append(errs, err)
+	}
+	for err := range filterErrs {
+		t.Errorf("Unexpected filter error: %v", err)
+	}
+
+	if !reflect.DeepEqual(ids, []int{1, 3}) {
+		t.Errorf("Expected events 1 and 3, got %v", ids)
+	}
+	if len(errs) != 2 || !strings.HasPrefix(errs[0].Error(), "line 2: ") || !strings.HasPrefix(errs[1].Error(), "line 4: ") {
+		t.Errorf("Expected decoding errors on lines 2 and 4, got %v", errs)
+	}
+
+	ids = nil
+	errs = nil
+	for e, err := range JSONLSourceSeq[event](strings.NewReader(content), DisallowUnknownFields()) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids = append(ids, e.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{1}) {
+		t.Errorf("Expected event 1 only, got %v", ids)
+	}
+	if len(errs) != 3 || !strings.Contains(errs[1].Error(), `line 3: error parsing JSON: json: unknown field "host"`) ||
+		!strings.Contains(errs[2].Error(), "line 4: error parsing JSON: invalid data after top-level value") {
+		t.Errorf("Expected errors on lines 2, 3 and 4, got %v", errs)
+	}
+}
+
+func TestJSONLCompressed(t *testing.T) {
+	dir := t.TempDir()
+	content := "{\"id\":1}\nnot json\n{\"id\":2}\n"
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(content))
+	w.Close()
+
+	// bzip2 of {"id":1}\n{"id":2}\n, the standard library has no bzip2 writer
+	bz := []byte("\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x32\x98\x4d\xda\x00\x00\x07\x59\x80\x00\x10\x10\x00\x30\x10\x04\x20\x00\x0a\x20\x00\x21\x28\x04\xfd\x50\x83\x26\x21\x38\x4f\x1a\x24\x9c\x2f\xc5\xdc\x91\x4e\x14\x24\x0c\xa6\x13\x76\x80")
+
+	files := map[string][]byte{
+		"data.jsonl.gz":  gz.Bytes(),
+		"gzip-data.log":  gz.Bytes(),
+		"data.jsonl.bz2": bz,
+		"fake.jsonl.gz":  []byte(content),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		ids      []float64
+		errorMsg string
+	}{
+		{"data.jsonl.gz", []float64{1, 2}, "line 2: error parsing JSON"},
+		{"gzip-data.log", []float64{1, 2}, "line 2: error parsing JSON"},
+		{"data.jsonl.bz2", []float64{1, 2}, ""},
+		{"fake.jsonl.gz", nil, "error reading file: gzip: invalid header"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ids []float64
+			var errs []string
+			for obj, err := range JSONLFileSourceSeq(filepath.Join(dir, tc.name)) {
+				if err != nil {
+					errs = append(errs, err.Error())
+					continue
+				}
+				ids = append(ids, obj.(map[string]interface{})["id"].(float64))
+			}
+
+			if !reflect.DeepEqual(ids, tc.ids) {
+				t.Errorf("Expected ids %v, got %v", tc.ids, ids)
+			}
+			if tc.errorMsg == "" && len(errs) > 0 || tc.errorMsg != "" && (len(errs) != 1 || !strings.Contains(errs[0], tc.errorMsg)) {
+				t.Errorf("Expected error %q, got %v", tc.errorMsg, errs)
+			}
+		})
+	}
+
+	results, errs := collectResults(JSONLReaderSourceStream(bytes.NewReader(gz.Bytes())))
+	if len(results) != 2 || len(errs) != 1 {
+		t.Errorf("Expected gzip data to be decompressed from readers, got %v and %v", results, errs)
+	}
+}
 
 func TestFilterCErrorHandling(t *testing.T) {
 	t.Run("normal_operation", func(t *testing.T) {
@@ -0,0 +1,85 @@
+package fq
+
+import "reflect"
+
+// Strict makes the comparisons (Gt, Gte, Lt, Lte) in query report values they can't be compared
+// with, instead of treating them as smaller than any operand. A mismatch stops Filter with an
+// *ErrTypeMismatch (FilterC reports it for the item and continues); the ErrTypeMismatch path is
+// relative to query. nil values, like missing fields, simply don't match. Called on its own,
+// the returned P doesn't match mismatched values either.
+// Operators nested in custom predicates are left as they are.
+func Strict(query Query) P {
+	strict := strictQuery(query, "")
+	return newOperator("strict", nil, []Query{query}, func(v interface{}) (matched bool) {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(evalError); !ok {
+					panic(r)
+				}
+				matched = false
+			}
+		}()
+		return eval(strict, v)
+	})
+}
+
+// strictQuery rebuilds query with strict comparisons, path is the field path of query
+func strictQuery(query Query, path string) Query {
+	switch q := query.(type) {
+	case Q:
+		return strictMapQuery(q, path)
+	case map[string]interface{}:
+		return strictMapQuery(q, path)
+	}
+
+	op := describe(query)
+	if op == nil {
+		return query
+	}
+
+	switch op.name {
+	case "gt", "gte", "lt", "lte":
+		return strictComparison(op.name, op.args[0], path)
+	case "and", "or", "not":
+		children := make([]Query, len(op.children))
+		for i, child := range op.children {
+			children[i] = strictQuery(child, path)
+		}
+		switch op.name {
+		case "and":
+			return And(children...)
+		case "or":
+			return Or(children...)
+		default:
+			return Not(children[0])
+		}
+	default:
+		return query
+	}
+}
+
+func strictMapQuery(query Q, path string) Q {
+	strict := make(Q, len(query))
+	for key, condition := range query {
+		strict[key] = strictQuery(condition, joinPath(path, key))
+	}
+	return strict
+}
+
+// strictComparison builds a gt/gte/lt/lte operator that panics with an evalError
+// on values that can't be compared with threshold
+func strictComparison(name string, threshold interface{}, path string) P {
+	accept := comparisonResult(name)
+	op := &operator{name: name, args: []interface{}{threshold}, strict: true}
+	return wrapOperator(op, func(v interface{}) bool {
+		if isNil(v) {
+			return false
+		}
+
+		c, ok := compare(v, threshold)
+		if !ok {
+			panic(evalError{&ErrTypeMismatch{Path: path, Op: name, Operand: threshold, Type: reflect.TypeOf(v)}})
+		}
+		return accept(c)
+	})
+}
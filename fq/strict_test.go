@@ -0,0 +1,87 @@
+package fq
+
+import (
+	"errors"
+	"testing"
+)
+
+func getMixedItems() []interface{} {
+	return []interface{}{
+		map[string]interface{}{"id": 1, "price": 3.5},
+		map[string]interface{}{"id": 2, "price": "cheap"},
+		map[string]interface{}{"id": 3, "price": 10},
+		map[string]interface{}{"id": 4},
+	}
+}
+
+func TestStrict(t *testing.T) {
+	items := getMixedItems()
+
+	// default comparisons match the string price and the missing one
+	result, err := Filter(items, Q{"price": Lt(5)}, 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != 3 {
+		t.Errorf("Expected 3 lenient matches, got %v", result)
+	}
+
+	// strict comparisons report the string price
+	result, err = Filter(items, Strict(Q{"price": Lt(5)}), 0, 0)
+	var mismatch *ErrTypeMismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected *ErrTypeMismatch, got %v", err)
+	}
+	if mismatch.Path != "price" || mismatch.Op != "lt" || mismatch.Operand != 5 || mismatch.Type.Kind().String() != "string" {
+		t.Errorf("Unexpected mismatch details: %+v", mismatch)
+	}
+
+	// nil values don't match, comparisons nested in logical operators are strict too
+	result, err = Filter(items, Strict(And(Q{"id": Not(Eq(2))}, Q{"price": Or(Lt(5), Gte(10))})), 0, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("Expected 2 strict matches, got %v", result)
+	}
+
+	// streaming reports the mismatch for the item and continues
+	input := make(chan interface{}, len(items))
+	for _, item := range items {
+		input <- item
+	}
+	close(input)
+
+	dataCh, errCh := FilterC(input, Strict(Q{"price": Gte(1)}), 0, 0)
+	results, errs := collectResults(dataCh, errCh)
+	if len(results) != 2 {
+		t.Errorf("Expected 2 streamed results, got %v", results)
+	}
+	if len(errs) != 1 || !errors.As(errs[0], &mismatch) {
+		t.Errorf("Expected a single *ErrTypeMismatch, got %v", errs)
+	}
+
+	// compiled strict queries behave the same
+	compiled, err := Compile[interface{}](Strict(Q{"price": Lt(5)}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := compiled.Filter(items, 0, 0); !errors.As(err, &mismatch) {
+		t.Errorf("Expected *ErrTypeMismatch from compiled query, got %v", err)
+	}
+	bad := map[string]interface{}{"price": "cheap"}
+	if matched, err := compiled.MatchErr(bad); matched || !errors.As(err, &mismatch) {
+		t.Errorf("Expected MatchErr to return *ErrTypeMismatch, got %v, %v", matched, err)
+	}
+	if matched, err := compiled.MatchErr(map[string]interface{}{"price": 1}); !matched || err != nil {
+		t.Errorf("Expected MatchErr to match, got %v, %v", matched, err)
+	}
+	if compiled.Match(bad) || Strict(Q{"price": Lt(5)})(bad) {
+		t.Error("Expected a mismatch not to match")
+	}
+
+	// struct fields are checked before filtering
+	if _, err := Filter(getTestProducts(), Strict(Q{"Name": Lt(5)}), 0, 0); !errors.As(err, &mismatch) {
+		t.Errorf("Expected *ErrTypeMismatch for struct field, got %v", err)
+	}
+}
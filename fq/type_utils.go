@@ -7,14 +7,25 @@ import (
 
 // compareValues compares two values
 func compareValues(a, b interface{}) int {
+	c, ok := compare(a, b)
+	if !ok {
+		// Types are not comparable - treat as not equal
+		return -1
+	}
+	return c
+}
+
+// compare orders two values of comparable types (nil sorts first),
+// ok is false when a and b can't be compared
+func compare(a, b interface{}) (int, bool) {
 	if a == nil && b == nil {
-		return 0
+		return 0, true
 	}
 	if a == nil {
-		return -1
+		return -1, true
 	}
 	if b == nil {
-		return 1
+		return 1, true
 	}
 
 	// fast path for common types
@@ -22,38 +33,38 @@ func compareValues(a, b interface{}) int {
 	case int:
 		if bVal, ok := b.(int); ok {
 			if aVal < bVal {
-				return -1
+				return -1, true
 			} else if aVal > bVal {
-				return 1
+				return 1, true
 			}
-			return 0
+			return 0, true
 		}
 	case float64:
 		if bVal, ok := b.(float64); ok {
 			if aVal < bVal {
-				return -1
+				return -1, true
 			} else if aVal > bVal {
-				return 1
+				return 1, true
 			}
-			return 0
+			return 0, true
 		}
 	case string:
 		if bVal, ok := b.(string); ok {
 			if aVal < bVal {
-				return -1
+				return -1, true
 			} else if aVal > bVal {
-				return 1
+				return 1, true
 			}
-			return 0
+			return 0, true
 		}
 	case time.Time:
 		if bVal, ok := b.(time.Time); ok {
 			if aVal.Before(bVal) {
-				return -1
+				return -1, true
 			} else if aVal.After(bVal) {
-				return 1
+				return 1, true
 			}
-			return 0
+			return 0, true
 		}
 	}
 
@@ -63,15 +74,14 @@ func compareValues(a, b interface{}) int {
 
 	if aIsNum && bIsNum {
 		if aNum < bNum {
-			return -1
+			return -1, true
 		} else if aNum > bNum {
-			return 1
+			return 1, true
 		}
-		return 0
+		return 0, true
 	}
 
-	// Types are not comparable - treat as not equal
-	return -1
+	return 0, false
 }
 
 // isEqual provides improved equality checking with safety for uncomparable types
@@ -0,0 +1,153 @@
+package fq
+
+import (
+	"math"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// Validate checks query against items of sampleType without evaluating it. It reports
+// unknown fields (*ErrUnknownField), operands that can never match their field (*ErrTypeMismatch)
+// and invalid operator arguments (*ErrInvalidOperand). Fields of interface type, and anything below
+// them, can only be checked at evaluation time. A nil sampleType checks operator arguments only,
+// like Record and *Record, whose Value queries are evaluated against. Struct fields are resolved
+// by their Go names, and by tag names too with WithTagNames.
+func Validate(query Query, sampleType reflect.Type, opts ...Option) error {
+	if sampleType == nil {
+		sampleType = anyType
+	}
+	_, err := compileItemQuery(query, sampleType, newOptions(opts).tagNames)
+	return err
+}
+
+// checkOperator validates the arguments of an operator, and that they can match values of type t
+func checkOperator(op *operator, t reflect.Type, path string) error {
+	invalid := func(operand interface{}, reason string) error {
+		return &ErrInvalidOperand{Path: path, Op: op.name, Operand: operand, Reason: reason}
+	}
+	mismatch := func(operand interface{}) error {
+		return &ErrTypeMismatch{Path: path, Op: op.name, Operand: operand, Type: t}
+	}
+
+	dynamic := t.Kind() == reflect.Interface
+
+	switch op.name {
+	case "eq":
+		if !canEqual(op.args[0], t) {
+			return mismatch(op.args[0])
+		}
+
+	case "gt", "gte", "lt", "lte":
+		operand := op.args[0]
+		if !isOrderable(operand) {
+			return invalid(operand, "not a number, string or time")
+		}
+		if !dynamic && !canCompare(operand, t) {
+			return mismatch(operand)
+		}
+
+	case "in":
+		for _, val := range op.args {
+			if val != nil && !dynamic && reflect.TypeOf(val) != t {
+				return mismatch(val)
+			}
+		}
+
+	case "contains":
+		if !dynamic && t != stringType {
+			return mismatch(op.args[0])
+		}
+
+	case "match":
+		switch pattern := op.args[0].(type) {
+		case string:
+		case *regexp.Regexp:
+			if pattern == nil {
+				return invalid(pattern, "nil pattern")
+			}
+		default:
+			return invalid(pattern, "not a string or *regexp.Regexp")
+		}
+		if !dynamic && t.Kind() != reflect.String {
+			return mismatch(op.args[0])
+		}
+
+	case "hasitem", "containsall", "containsany":
+		if dynamic {
+			break
+		}
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return mismatch(op.args)
+		}
+		elem := t.Elem()
+		for _, item := range op.args {
+			if elem.Kind() != reflect.Interface && reflect.TypeOf(item) != elem {
+				return &ErrTypeMismatch{Path: path, Op: op.name, Operand: item, Type: t}
+			}
+		}
+
+	case "geowithin":
+		lat, lng, radius := op.args[0].(float64), op.args[1].(float64), op.args[2].(float64)
+		if math.IsNaN(lat) || lat < -90 || lat > 90 {
+			return invalid(lat, "latitude out of range [-90, 90]")
+		}
+		if math.IsNaN(lng) || lng < -180 || lng > 180 {
+			return invalid(lng, "longitude out of range [-180, 180]")
+		}
+		if math.IsNaN(radius) || radius < 0 {
+			return invalid(radius, "negative radius")
+		}
+		if dynamic {
+			break
+		}
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return mismatch(op.args)
+		}
+		if elem := t.Elem(); elem.Kind() != reflect.Interface && numberOf(elem) == nil {
+			return mismatch(op.args)
+		}
+	}
+
+	return nil
+}
+
+// canEqual checks if a plain value operand can be equal to values of type t (see isEqual).
+// nil is always accepted, missing map keys and out of range indexes resolve to nil.
+func canEqual(val interface{}, t reflect.Type) bool {
+	if val == nil || t.Kind() == reflect.Interface {
+		return true
+	}
+	if _, ok := toNumber(val); ok {
+		return numberOf(t) != nil
+	}
+	return reflect.TypeOf(val) == t
+}
+
+// canCompare checks if compareValues can order values of type t against operand
+func canCompare(operand interface{}, t reflect.Type) bool {
+	if _, ok := toNumber(operand); ok {
+		return numberOf(t) != nil
+	}
+	switch operand.(type) {
+	case string:
+		return t == stringType
+	case time.Time:
+		return t == timeType
+	default:
+		return false
+	}
+}
+
+// isOrderable checks if compareValues can order v against values of its own kind
+func isOrderable(v interface{}) bool {
+	if _, ok := toNumber(v); ok {
+		return true
+	}
+	switch v.(type) {
+	case string, time.Time:
+		return true
+	default:
+		return false
+	}
+}
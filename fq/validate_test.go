@@ -0,0 +1,145 @@
+package fq
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	productType := reflect.TypeOf(Product{})
+
+	type status string
+	type Item struct {
+		Status status
+		Value  interface{}
+		Coords [2]float64
+	}
+
+	tests := []struct {
+		name      string
+		query     Query
+		itemType  reflect.Type
+		errorType string // "", "unknown", "mismatch" or "invalid"
+		path      string
+		op        string
+	}{
+		{"valid query", Q{"Price": Lt(500), "Name": Contains("Pro"), "Tags": HasItem("work")}, productType, "", "", ""},
+		{"valid nested logic", And(Q{"ID": Or(1, 2.0)}, Not(Q{"Address.City": In("Paris")})), productType, "", "", ""},
+		{"valid nil literal", Q{"Tags.3": nil, "Properties.color": Eq(nil)}, productType, "", "", ""},
+		{"valid time", Q{"CreatedAt": Gte(time.Now())}, productType, "", "", ""},
+		{"valid dynamic field", Q{"Properties.warranty": Gt("x")}, productType, "", "", ""},
+		{"valid interface field", Q{"Value": HasItem(1), "Value.nested.0": 2}, reflect.TypeOf(Item{}), "", "", ""},
+		{"valid geo", Q{"Coords": GeoWithin(40.7, -74.0, 10)}, reflect.TypeOf(Item{}), "", "", ""},
+		{"valid named string", Q{"Status": status("active"), "Value": Match("x")}, reflect.TypeOf(Item{}), "", "", ""},
+		{"valid custom predicate", Q{"Price": func(v interface{}) bool { return true }}, productType, "", "", ""},
+
+		{"unknown field", Q{"Nmae": "Laptop"}, productType, "unknown", "Nmae", ""},
+		{"unknown nested field", Q{"Manufacturer": Q{"City": "x"}}, productType, "unknown", "Manufacturer.City", ""},
+		{"unknown field in logic", Or(Q{"ID": 1}, Q{"Id": 2}), productType, "unknown", "Id", ""},
+
+		{"string against int", Q{"ID": "1"}, productType, "mismatch", "ID", "eq"},
+		{"gt string against float", Q{"Price": Gt("abc")}, productType, "mismatch", "Price", "gt"},
+		{"lt int against string", Q{"Name": Lt(5)}, productType, "mismatch", "Name", "lt"},
+		{"plain string against named string", Q{"Status": "active"}, reflect.TypeOf(Item{}), "mismatch", "Status", "eq"},
+		{"in float against int", Q{"Stock": In(1.0, 2.0)}, productType, "mismatch", "Stock", "in"},
+		{"contains on slice", Q{"Tags": Contains("x")}, productType, "mismatch", "Tags", "contains"},
+		{"hasitem on string", Q{"Name": HasItem("x")}, productType, "mismatch", "Name", "hasitem"},
+		{"hasitem wrong element type", Q{"Tags": ContainsAny("x", 1)}, productType, "mismatch", "Tags", "containsany"},
+		{"mismatch under not", Q{"Stock": Not(Eq(true))}, productType, "mismatch", "Stock", "eq"},
+
+		{"incomparable gt", Q{"Tags": Gt([]string{"a"})}, productType, "invalid", "Tags", "gt"},
+		{"invalid pattern", Q{"Name": Match(1)}, productType, "invalid", "Name", "match"},
+		{"invalid latitude", Q{"": GeoWithin(91, 0, 1)}, nil, "invalid", "", "geowithin"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.query, tc.itemType)
+
+			var unknown *ErrUnknownField
+			var mismatch *ErrTypeMismatch
+			var invalid *ErrInvalidOperand
+
+			switch tc.errorType {
+			case "":
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			case "unknown":
+				if !errors.As(err, &unknown) {
+					t.Fatalf("Expected *ErrUnknownField, got %v", err)
+				}
+				if unknown.Path != tc.path {
+					t.Errorf("Expected path %q, got %q", tc.path, unknown.Path)
+				}
+			case "mismatch":
+				if !errors.As(err, &mismatch) {
+					t.Fatalf("Expected *ErrTypeMismatch, got %v", err)
+				}
+				if mismatch.Path != tc.path || mismatch.Op != tc.op {
+					t.Errorf("Expected %s on %q, got %s on %q", tc.op, tc.path, mismatch.Op, mismatch.Path)
+				}
+			case "invalid":
+				if !errors.As(err, &invalid) {
+					t.Fatalf("Expected *ErrInvalidOperand, got %v", err)
+				}
+				if invalid.Path != tc.path || invalid.Op != tc.op {
+					t.Errorf("Expected %s on %q, got %s on %q", tc.op, tc.path, invalid.Op, invalid.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterValidationErrors(t *testing.T) {
+	products := getTestProducts()
+
+	result, err := Filter(products, Q{"Price": Gt("abc")}, 0, 0)
+	var mismatch *ErrTypeMismatch
+	if !errors.As(err, &mismatch) {
+		t.Errorf("Expected *ErrTypeMismatch from Filter, got %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("Expected no results, got %d", len(result))
+	}
+
+	input := make(chan Product, len(products))
+	for _, product := range products {
+		input <- product
+	}
+	close(input)
+
+	dataCh, errCh := FilterC(input, Q{"Nmae": "Laptop Pro"}, 0, 0)
+	results, errs := collectResults(chanToInterface(dataCh), errCh)
+
+	var unknown *ErrUnknownField
+	if len(errs) != 1 || !errors.As(errs[0], &unknown) {
+		t.Errorf("Expected a single *ErrUnknownField from FilterC, got %v", errs)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected no results, got %d", len(results))
+	}
+
+	// maps and interfaces are only checked for operands
+	items := []interface{}{map[string]interface{}{"a": 1}}
+	if _, err := Filter(items, Q{"b": 1}, 0, 0); err != nil {
+		t.Errorf("Unexpected error for dynamic items: %v", err)
+	}
+	var invalid *ErrInvalidOperand
+	if _, err := Filter(items, Q{"a": Lt(nil)}, 0, 0); !errors.As(err, &invalid) {
+		t.Errorf("Expected *ErrInvalidOperand for dynamic items, got %v", err)
+	}
+}
+
+func chanToInterface[T any](ch <-chan T) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		for v := range ch {
+			out <- v
+		}
+	}()
+	return out
+}